@@ -4,7 +4,9 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
@@ -28,12 +30,20 @@ type Config struct {
 	Expiry    Expiry    `json:"expiry"`
 	Logger    Logger    `json:"logger"`
 
+	// ClientPolicy is checked against static clients at startup and clients
+	// created through the gRPC API.
+	ClientPolicy ClientPolicy `json:"clientPolicy"`
+
 	Frontend server.WebConfig `json:"frontend"`
 
 	// StaticConnectors are user defined connectors specified in the ConfigMap
 	// Write operations, like updating a connector, will fail.
 	StaticConnectors []Connector `json:"connectors"`
 
+	// ConnectorsDir is an optional directory of JSON files, each holding a single
+	// connector. These are loaded at startup and added to the static connectors.
+	ConnectorsDir string `json:"connectorsDir"`
+
 	// StaticClients cause the server to use this list of clients rather than
 	// querying the storage. Write operations, like creating a client, will fail.
 	StaticClients []storage.Client `json:"staticClients"`
@@ -211,6 +221,53 @@ func (c *Connector) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// loadConnectorsFromDir parses each "*.json" file in dir as a connector. Errors
+// report the file that failed to parse or validate.
+//
+// Connector IDs must be unique across the files and the connectors already
+// defined in the config file, which are passed as existing.
+func loadConnectorsFromDir(dir string, existing []Connector) ([]Connector, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read connectors dir: %v", err)
+	}
+
+	// Where each connector ID was defined, for reporting duplicates.
+	definedIn := make(map[string]string, len(existing))
+	for _, c := range existing {
+		definedIn[c.ID] = "the config file"
+	}
+
+	var connectors []Connector
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read connector file %s: %v", path, err)
+		}
+
+		var c Connector
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("connector file %s: %v", path, err)
+		}
+		if c.ID == "" || c.Name == "" || c.Type == "" {
+			return nil, fmt.Errorf("connector file %s: ID, Type and Name fields are required for a connector", path)
+		}
+		if c.Config == nil {
+			return nil, fmt.Errorf("connector file %s: no config field for connector %q", path, c.ID)
+		}
+		if other, ok := definedIn[c.ID]; ok {
+			return nil, fmt.Errorf("connector file %s: connector ID %q is already defined in %s", path, c.ID, other)
+		}
+		definedIn[c.ID] = path
+		connectors = append(connectors, c)
+	}
+	return connectors, nil
+}
+
 // ToStorageConnector converts an object to storage connector type.
 func ToStorageConnector(c Connector) (storage.Connector, error) {
 	data, err := json.Marshal(c.Config)
@@ -242,4 +299,28 @@ type Logger struct {
 
 	// Format specifies the format to be used for logging.
 	Format string `json:"format"`
+
+	// ClientIDSalt, if set, causes client IDs to be logged as a salted hash
+	// rather than in plain text.
+	ClientIDSalt string `json:"clientIDSalt"`
+}
+
+// ClientPolicy holds the rules clients must follow. Rules are only checked
+// when set.
+type ClientPolicy struct {
+	// RequireHTTPSRedirects rejects redirect URIs which don't use https, except
+	// for loopback addresses.
+	RequireHTTPSRedirects bool `json:"requireHTTPSRedirects"`
+
+	// AllowedRedirectSchemes are additional schemes redirect URIs may use, such
+	// as the private-use schemes of native apps. Setting any implies
+	// requireHTTPSRedirects.
+	AllowedRedirectSchemes []string `json:"allowedRedirectSchemes"`
+
+	// RequireSecrets rejects clients which aren't public and have no secret.
+	RequireSecrets bool `json:"requireSecrets"`
+
+	// MinSecretLength is the minimum length of secrets supplied for clients
+	// which aren't public. Secrets generated by the gRPC API aren't checked.
+	MinSecretLength int `json:"minSecretLength"`
 }
@@ -1,6 +1,10 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/coreos/dex/connector/mock"
@@ -63,6 +67,13 @@ expiry:
 logger:
   level: "debug"
   format: "json"
+
+clientPolicy:
+  requireHTTPSRedirects: true
+  allowedRedirectSchemes:
+  - com.example.app
+  requireSecrets: true
+  minSecretLength: 16
 `)
 
 	want := Config{
@@ -128,6 +139,12 @@ logger:
 			Level:  "debug",
 			Format: "json",
 		},
+		ClientPolicy: ClientPolicy{
+			RequireHTTPSRedirects:  true,
+			AllowedRedirectSchemes: []string{"com.example.app"},
+			RequireSecrets:         true,
+			MinSecretLength:        16,
+		},
 	}
 
 	var c Config
@@ -139,3 +156,74 @@ logger:
 	}
 
 }
+
+func TestLoadConnectorsFromDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dex-connectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"google.json": `{"type":"oidc","id":"google","name":"Google","config":{"issuer":"https://accounts.google.com","clientID":"foo"}}`,
+		"mock.json":   `{"type":"mockCallback","id":"mock","name":"Example","config":{}}`,
+		"README.md":   `not a connector`,
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := loadConnectorsFromDir(dir, nil)
+	if err != nil {
+		t.Fatalf("load connectors: %v", err)
+	}
+	want := []Connector{
+		{
+			Type: "oidc",
+			ID:   "google",
+			Name: "Google",
+			Config: &oidc.Config{
+				Issuer:   "https://accounts.google.com",
+				ClientID: "foo",
+			},
+		},
+		{
+			Type:   "mockCallback",
+			ID:     "mock",
+			Name:   "Example",
+			Config: &mock.CallbackConfig{},
+		},
+	}
+	if diff := pretty.Compare(got, want); diff != "" {
+		t.Errorf("got!=want: %s", diff)
+	}
+
+	// A connector ID already used in the config file.
+	inline := []Connector{{Type: "mockCallback", ID: "mock", Name: "Inline"}}
+	mockFile := filepath.Join(dir, "mock.json")
+	if _, err := loadConnectorsFromDir(dir, inline); err == nil || !strings.Contains(err.Error(), mockFile) {
+		t.Errorf("expected duplicate error naming %s, got %v", mockFile, err)
+	}
+
+	// A connector ID used by two files.
+	dup := filepath.Join(dir, "mock2.json")
+	if err := ioutil.WriteFile(dup, []byte(files["mock.json"]), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConnectorsFromDir(dir, nil); err == nil || !strings.Contains(err.Error(), dup) || !strings.Contains(err.Error(), mockFile) {
+		t.Errorf("expected duplicate error naming %s and %s, got %v", dup, mockFile, err)
+	}
+	if err := os.Remove(dup); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := ioutil.WriteFile(bad, []byte(`{"type":"oidc","name":"No ID","config":{}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConnectorsFromDir(dir, nil); err == nil || !strings.Contains(err.Error(), bad) {
+		t.Errorf("expected error naming %s, got %v", bad, err)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func commandPendingMigrations() *cobra.Command {
+	return &cobra.Command{
+		Use:     "pending-migrations [ config file ]",
+		Short:   "Print the storage migrations which haven't been applied, without applying them.",
+		Long:    ``,
+		Example: "dex pending-migrations config.yaml",
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := pendingMigrations(os.Stdout, args); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+		},
+	}
+}
+
+// pendingMigrationsLister is implemented by storage configs which can report
+// the migrations Open would apply, such as the SQL storages.
+type pendingMigrationsLister interface {
+	PendingMigrations(logrus.FieldLogger) ([]string, error)
+}
+
+func pendingMigrations(w io.Writer, args []string) error {
+	switch len(args) {
+	default:
+		return errors.New("surplus arguments")
+	case 0:
+		return errors.New("no arguments provided")
+	case 1:
+	}
+
+	configFile := args[0]
+	configData, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to read config file %s: %v", configFile, err)
+	}
+
+	var c Config
+	if err := yaml.Unmarshal(configData, &c); err != nil {
+		return fmt.Errorf("error parse config file %s: %v", configFile, err)
+	}
+	if c.Storage.Config == nil {
+		return errors.New("invalid config: no storage supplied in config file")
+	}
+
+	logger, err := newLogger(c.Logger.Level, c.Logger.Format)
+	if err != nil {
+		return fmt.Errorf("invalid config: %v", err)
+	}
+
+	lister, ok := c.Storage.Config.(pendingMigrationsLister)
+	if !ok {
+		return fmt.Errorf("storage %q doesn't have migrations", c.Storage.Type)
+	}
+	stmts, err := lister.PendingMigrations(logger)
+	if err != nil {
+		return fmt.Errorf("failed to list pending migrations: %v", err)
+	}
+	for _, stmt := range stmts {
+		fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(stmt))
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coreos/dex/storage/sql"
+)
+
+func TestPendingMigrations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dex-migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dbFile := filepath.Join(dir, "dex.db")
+	configFile := filepath.Join(dir, "config.yaml")
+	config := []byte(`
+storage:
+  type: sqlite3
+  config:
+    file: ` + dbFile + `
+`)
+	if err := ioutil.WriteFile(configFile, config, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	buff := new(bytes.Buffer)
+	if err := pendingMigrations(buff, []string{configFile}); err != nil {
+		t.Fatalf("pending migrations: %v", err)
+	}
+	if buff.Len() == 0 {
+		t.Errorf("expected pending migrations for a new database")
+	}
+	if _, err := os.Stat(dbFile); !os.IsNotExist(err) {
+		t.Errorf("expected listing pending migrations not to create the database file, got %v", err)
+	}
+
+	logger := &logrus.Logger{Out: ioutil.Discard, Formatter: &logrus.TextFormatter{}}
+	s, err := (&sql.SQLite3{File: dbFile}).Open(logger)
+	if err != nil {
+		t.Fatalf("open storage: %v", err)
+	}
+	s.Close()
+
+	buff.Reset()
+	if err := pendingMigrations(buff, []string{configFile}); err != nil {
+		t.Fatalf("pending migrations: %v", err)
+	}
+	if buff.Len() != 0 {
+		t.Errorf("expected no pending migrations after opening the storage, got %q", buff.String())
+	}
+}
@@ -96,6 +96,37 @@ func serve(cmd *cobra.Command, args []string) error {
 
 	logger.Infof("config issuer: %s", c.Issuer)
 
+	// Check the static clients and connectors before opening the storage, which
+	// may run migrations or wait for the database to be reachable. Errors name
+	// clients by their plain ID so they can be found in the config file.
+	clientPolicy := server.ClientPolicy{
+		RequireHTTPSRedirects:  c.ClientPolicy.RequireHTTPSRedirects,
+		AllowedRedirectSchemes: c.ClientPolicy.AllowedRedirectSchemes,
+		RequireSecrets:         c.ClientPolicy.RequireSecrets,
+		MinSecretLength:        c.ClientPolicy.MinSecretLength,
+	}
+	for _, client := range c.StaticClients {
+		for _, uri := range client.RedirectURIs {
+			if err := clientPolicy.ValidateRedirectURI(uri); err != nil {
+				return fmt.Errorf("invalid config: static client %q: %v", client.ID, err)
+			}
+		}
+		if !client.Public {
+			if err := clientPolicy.ValidateSecret(client.Secret); err != nil {
+				return fmt.Errorf("invalid config: static client %q: %v", client.ID, err)
+			}
+		}
+	}
+
+	if c.ConnectorsDir != "" {
+		dirConnectors, err := loadConnectorsFromDir(c.ConnectorsDir, c.StaticConnectors)
+		if err != nil {
+			return fmt.Errorf("invalid config: %v", err)
+		}
+		logger.Infof("config connectors dir: %s", c.ConnectorsDir)
+		c.StaticConnectors = append(c.StaticConnectors, dirConnectors...)
+	}
+
 	prometheusRegistry := prometheus.NewRegistry()
 	err = prometheusRegistry.Register(prometheus.NewGoCollector())
 	if err != nil {
@@ -113,6 +144,10 @@ func serve(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to register gRPC server metrics: %v", err)
 	}
 
+	if err := server.RegisterAPIMetrics(prometheusRegistry); err != nil {
+		return fmt.Errorf("failed to register gRPC API metrics: %v", err)
+	}
+
 	var grpcOptions []grpc.ServerOption
 
 	if c.GRPC.TLSCert != "" {
@@ -158,9 +193,20 @@ func serve(cmd *cobra.Command, args []string) error {
 	}
 	logger.Infof("config storage: %s", c.Storage.Type)
 
+	// Storages may expose their own metrics, such as SQL connection pool usage.
+	if collector, ok := s.(prometheus.Collector); ok {
+		if err := prometheusRegistry.Register(collector); err != nil {
+			return fmt.Errorf("failed to register storage metrics: %v", err)
+		}
+	}
+
 	if len(c.StaticClients) > 0 {
 		for _, client := range c.StaticClients {
-			logger.Infof("config static client: %s", client.ID)
+			id := client.ID
+			if c.Logger.ClientIDSalt != "" {
+				id = server.HashClientIDForLog(c.Logger.ClientIDSalt, id)
+			}
+			logger.Infof("config static client: %s", id)
 		}
 		s = storage.WithStaticClients(s, c.StaticClients)
 	}
@@ -205,6 +251,9 @@ func serve(cmd *cobra.Command, args []string) error {
 	if len(c.OAuth2.ResponseTypes) > 0 {
 		logger.Infof("config response types accepted: %s", c.OAuth2.ResponseTypes)
 	}
+	if c.Logger.ClientIDSalt != "" {
+		logger.Infof("config logging hashed client IDs")
+	}
 	if c.OAuth2.SkipApprovalScreen {
 		logger.Infof("config skipping approval screen")
 	}
@@ -223,6 +272,8 @@ func serve(cmd *cobra.Command, args []string) error {
 		Storage:                s,
 		Web:                    c.Frontend,
 		Logger:                 logger,
+		ClientIDLogSalt:        c.Logger.ClientIDSalt,
+		ClientPolicy:           clientPolicy,
 		Now:                    now,
 		PrometheusRegistry:     prometheusRegistry,
 	}
@@ -282,7 +333,10 @@ func serve(cmd *cobra.Command, args []string) error {
 					return fmt.Errorf("listening on %s failed: %v", c.GRPC.Addr, err)
 				}
 				s := grpc.NewServer(grpcOptions...)
-				api.RegisterDexServer(s, server.NewAPI(serverConfig.Storage, logger))
+				api.RegisterDexServer(s, server.NewAPIWithConfig(serverConfig.Storage, logger, server.APIConfig{
+					ClientIDLogSalt: serverConfig.ClientIDLogSalt,
+					ClientPolicy:    serverConfig.ClientPolicy,
+				}))
 				grpcMetrics.InitializeMetrics(s)
 				err = s.Serve(list)
 				return fmt.Errorf("listening on %s failed: %v", c.GRPC.Addr, err)
@@ -3,18 +3,14 @@ package github
 
 import (
 	"context"
-	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/github"
@@ -45,7 +41,10 @@ type Config struct {
 	Org          string `json:"org"`
 	Orgs         []Org  `json:"orgs"`
 	HostName     string `json:"hostName"`
-	RootCA       string `json:"rootCA"`
+
+	// Optional settings for the HTTP client used to talk to GitHub. A root CA
+	// may only be set for GitHub Enterprise.
+	connector.HTTPClientConfig
 }
 
 // Org holds org-team filters, in which teams are optional.
@@ -93,17 +92,13 @@ func (c *Config) Open(id string, logger logrus.FieldLogger) (connector.Connector
 		g.apiURL = "https://" + c.HostName + "/api/v3"
 	}
 
-	if c.RootCA != "" {
-		if c.HostName == "" {
-			return nil, errors.New("invalid connector config: Host name field required for a root certificate file")
-		}
-		g.rootCA = c.RootCA
-
-		var err error
-		if g.httpClient, err = newHTTPClient(g.rootCA); err != nil {
-			return nil, fmt.Errorf("failed to create HTTP client: %v", err)
-		}
+	if c.RootCA != "" && c.HostName == "" {
+		return nil, errors.New("invalid connector config: Host name field required for a root certificate file")
+	}
 
+	var err error
+	if g.httpClient, err = c.NewHTTPClient(); err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %v", err)
 	}
 
 	return &g, nil
@@ -130,9 +125,7 @@ type githubConnector struct {
 	apiURL string
 	// hostName of the GitHub enterprise account.
 	hostName string
-	// Used to support untrusted/self-signed CA certs.
-	rootCA string
-	// HTTP Client that trusts the custom delcared rootCA cert.
+	// HTTP Client built from the connector's HTTP client config, if any.
 	httpClient *http.Client
 }
 
@@ -188,32 +181,13 @@ func (e *oauth2Error) Error() string {
 	return e.error + ": " + e.errorDescription
 }
 
-// newHTTPClient returns a new HTTP client that trusts the custom delcared rootCA cert.
-func newHTTPClient(rootCA string) (*http.Client, error) {
-	tlsConfig := tls.Config{RootCAs: x509.NewCertPool()}
-	rootCABytes, err := ioutil.ReadFile(rootCA)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read root-ca: %v", err)
-	}
-	if !tlsConfig.RootCAs.AppendCertsFromPEM(rootCABytes) {
-		return nil, fmt.Errorf("no certs found in root CA file %q", rootCA)
-	}
-
-	return &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tlsConfig,
-			Proxy:           http.ProxyFromEnvironment,
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-				DualStack: true,
-			}).DialContext,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		},
-	}, nil
+// withHTTPClient returns a context which makes golang.org/x/oauth2 use the
+// connector's HTTP client, if one is configured.
+func (c *githubConnector) withHTTPClient(ctx context.Context) context.Context {
+	if c.httpClient == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, c.httpClient)
 }
 
 func (c *githubConnector) HandleCallback(s connector.Scopes, r *http.Request) (identity connector.Identity, err error) {
@@ -224,11 +198,7 @@ func (c *githubConnector) HandleCallback(s connector.Scopes, r *http.Request) (i
 
 	oauth2Config := c.oauth2Config(s)
 
-	ctx := r.Context()
-	// GitHub Enterprise account
-	if c.httpClient != nil {
-		ctx = context.WithValue(r.Context(), oauth2.HTTPClient, c.httpClient)
-	}
+	ctx := c.withHTTPClient(r.Context())
 
 	token, err := oauth2Config.Exchange(ctx, q.Get("code"))
 	if err != nil {
@@ -284,6 +254,7 @@ func (c *githubConnector) Refresh(ctx context.Context, s connector.Scopes, ident
 		return identity, fmt.Errorf("github: unmarshal access token: %v", err)
 	}
 
+	ctx = c.withHTTPClient(ctx)
 	client := c.oauth2Config(s).Client(ctx, &oauth2.Token{AccessToken: data.AccessToken})
 	user, err := c.user(ctx, client)
 	if err != nil {
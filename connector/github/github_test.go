@@ -0,0 +1,53 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coreos/dex/connector"
+)
+
+// countingTransport counts the requests sent through it.
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestRefreshUsesHTTPClient(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(user{Name: "Jane Doe", Login: "jane", ID: 1, Email: "jane@example.com"})
+	}))
+	defer s.Close()
+
+	transport := &countingTransport{}
+	c := &githubConnector{
+		apiURL:     s.URL,
+		httpClient: &http.Client{Transport: transport},
+	}
+
+	data, err := json.Marshal(connectorData{AccessToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	identity, err := c.Refresh(context.Background(), connector.Scopes{}, connector.Identity{ConnectorData: data})
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if identity.Username != "Jane Doe" || identity.Email != "jane@example.com" {
+		t.Errorf("unexpected identity: %+v", identity)
+	}
+	if transport.requests == 0 {
+		t.Errorf("expected refresh to use the connector's HTTP client")
+	}
+}
@@ -0,0 +1,88 @@
+package connector
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HTTPClientConfig holds options for the HTTP client a connector uses to talk to
+// its upstream provider. Connectors embed it in their config so its fields sit
+// alongside the connector's own.
+type HTTPClientConfig struct {
+	// Path to a PEM encoded root CA bundle used to verify the provider's TLS
+	// certificate, for providers behind a self-signed or internal CA.
+	RootCA string `json:"rootCA"`
+
+	// Skip verifying the provider's TLS certificate. Only meant for testing.
+	InsecureSkipVerify bool `json:"insecureSkipVerify"`
+
+	// URL of the proxy to send requests through. Defaults to the proxy set by
+	// the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+	ProxyURL string `json:"proxyURL"`
+
+	// Time limit for each request, such as "10s". Defaults to no limit.
+	Timeout string `json:"timeout"`
+}
+
+// NewHTTPClient returns an HTTP client built from the config. It returns nil if
+// no options are set, in which case the connector should use its default client.
+func (c HTTPClientConfig) NewHTTPClient() (*http.Client, error) {
+	if c == (HTTPClientConfig{}) {
+		return nil, nil
+	}
+
+	tlsConfig := tls.Config{InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.RootCA != "" {
+		tlsConfig.RootCAs = x509.NewCertPool()
+		rootCABytes, err := ioutil.ReadFile(c.RootCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read root-ca: %v", err)
+		}
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(rootCABytes) {
+			return nil, fmt.Errorf("no certs found in root CA file %q", c.RootCA)
+		}
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if c.ProxyURL != "" {
+		proxyURL, err := url.Parse(c.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %v", c.ProxyURL, err)
+		}
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q: scheme and host are required", c.ProxyURL)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	var timeout time.Duration
+	if c.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(c.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout %q: %v", c.Timeout, err)
+		}
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tlsConfig,
+			Proxy:           proxy,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+				DualStack: true,
+			}).DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+	}, nil
+}
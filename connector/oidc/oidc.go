@@ -36,6 +36,10 @@ type Config struct {
 	// Optional list of whitelisted domains when using Google
 	// If this field is nonempty, only users from a listed domain will be allowed to log in
 	HostedDomains []string `json:"hostedDomains"`
+
+	// Optional settings for the HTTP client used to talk to the issuer, such
+	// as a root CA for providers behind a self-signed or internal CA.
+	connector.HTTPClientConfig
 }
 
 // Domains that don't support basic auth. golang.org/x/oauth2 has an internal
@@ -76,7 +80,16 @@ func registerBrokenAuthHeaderProvider(url string) {
 // Open returns a connector which can be used to login users through an upstream
 // OpenID Connect provider.
 func (c *Config) Open(id string, logger logrus.FieldLogger) (conn connector.Connector, err error) {
+	httpClient, err := c.NewHTTPClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %v", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
+	if httpClient != nil {
+		// Used for discovery, and by the verifier to fetch the issuer's keys.
+		ctx = oidc.ClientContext(ctx, httpClient)
+	}
 
 	provider, err := oidc.NewProvider(ctx, c.Issuer)
 	if err != nil {
@@ -116,6 +129,7 @@ func (c *Config) Open(id string, logger logrus.FieldLogger) (conn connector.Conn
 		logger:        logger,
 		cancel:        cancel,
 		hostedDomains: c.HostedDomains,
+		httpClient:    httpClient,
 	}, nil
 }
 
@@ -132,6 +146,9 @@ type oidcConnector struct {
 	cancel        context.CancelFunc
 	logger        logrus.FieldLogger
 	hostedDomains []string
+
+	// HTTP client built from the connector's HTTP client config, if any.
+	httpClient *http.Client
 }
 
 func (c *oidcConnector) Close() error {
@@ -171,7 +188,11 @@ func (c *oidcConnector) HandleCallback(s connector.Scopes, r *http.Request) (ide
 	if errType := q.Get("error"); errType != "" {
 		return identity, &oauth2Error{errType, q.Get("error_description")}
 	}
-	token, err := c.oauth2Config.Exchange(r.Context(), q.Get("code"))
+	ctx := r.Context()
+	if c.httpClient != nil {
+		ctx = oidc.ClientContext(ctx, c.httpClient)
+	}
+	token, err := c.oauth2Config.Exchange(ctx, q.Get("code"))
 	if err != nil {
 		return identity, fmt.Errorf("oidc: failed to get token: %v", err)
 	}
@@ -180,7 +201,7 @@ func (c *oidcConnector) HandleCallback(s connector.Scopes, r *http.Request) (ide
 	if !ok {
 		return identity, errors.New("oidc: no id_token in token response")
 	}
-	idToken, err := c.verifier.Verify(r.Context(), rawIDToken)
+	idToken, err := c.verifier.Verify(ctx, rawIDToken)
 	if err != nil {
 		return identity, fmt.Errorf("oidc: failed to verify ID Token: %v", err)
 	}
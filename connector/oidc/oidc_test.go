@@ -1,7 +1,18 @@
 package oidc
 
 import (
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/coreos/dex/connector"
 )
 
 func TestKnownBrokenAuthHeaderProvider(t *testing.T) {
@@ -23,3 +34,67 @@ func TestKnownBrokenAuthHeaderProvider(t *testing.T) {
 		}
 	}
 }
+
+func TestOpenWithHTTPClientConfig(t *testing.T) {
+	var issuer string
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 issuer,
+			"authorization_endpoint": issuer + "/auth",
+			"token_endpoint":         issuer + "/token",
+			"jwks_uri":               issuer + "/keys",
+		})
+	}))
+	defer s.Close()
+	issuer = s.URL
+
+	dir, err := ioutil.TempDir("", "dex-oidc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootCA := filepath.Join(dir, "ca.crt")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.TLS.Certificates[0].Certificate[0]})
+	if err := ioutil.WriteFile(rootCA, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	notCA := filepath.Join(dir, "not-a-ca.crt")
+	if err := ioutil.WriteFile(notCA, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := &logrus.Logger{Out: ioutil.Discard, Formatter: &logrus.TextFormatter{}}
+	tests := []struct {
+		name    string
+		config  connector.HTTPClientConfig
+		wantErr bool
+	}{
+		{"trusted root CA", connector.HTTPClientConfig{RootCA: rootCA}, false},
+		{"no root CA", connector.HTTPClientConfig{}, true},
+		{"missing root CA file", connector.HTTPClientConfig{RootCA: filepath.Join(dir, "missing.crt")}, true},
+		{"root CA file without certs", connector.HTTPClientConfig{RootCA: notCA}, true},
+		{"skip verify", connector.HTTPClientConfig{InsecureSkipVerify: true}, false},
+		{"timeout", connector.HTTPClientConfig{RootCA: rootCA, Timeout: "10s"}, false},
+		{"invalid timeout", connector.HTTPClientConfig{RootCA: rootCA, Timeout: "ten seconds"}, true},
+		{"invalid proxy URL", connector.HTTPClientConfig{RootCA: rootCA, ProxyURL: "proxy:3128"}, true},
+	}
+	for _, tc := range tests {
+		c := &Config{Issuer: issuer, HTTPClientConfig: tc.config}
+		conn, err := c.Open("oidc", logger)
+		if err != nil {
+			if !tc.wantErr {
+				t.Errorf("%s: open: %v", tc.name, err)
+			}
+			continue
+		}
+		conn.(*oidcConnector).Close()
+		if tc.wantErr {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	// go-grpc doesn't use the standard library's context.
 	// https://github.com/grpc/grpc-go/issues/711
@@ -14,6 +16,7 @@ import (
 	"github.com/coreos/dex/server/internal"
 	"github.com/coreos/dex/storage"
 	"github.com/coreos/dex/version"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
 
@@ -32,17 +35,69 @@ const (
 	upBoundCost = 16
 )
 
+// Outcome labels for the client churn metrics.
+const (
+	outcomeSuccess       = "success"
+	outcomeAlreadyExists = "already_exists"
+	outcomeInvalid       = "invalid"
+	outcomeNotFound      = "not_found"
+	outcomeError         = "error"
+)
+
+var (
+	counterClientsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_clients_created_total",
+		Help: "Count of client registrations through the gRPC API, by outcome.",
+	}, []string{"outcome"})
+
+	counterClientsDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "api_clients_deleted_total",
+		Help: "Count of client deletions through the gRPC API, by outcome.",
+	}, []string{"outcome"})
+)
+
+// RegisterAPIMetrics registers the gRPC API's metrics with the provided registry.
+func RegisterAPIMetrics(r *prometheus.Registry) error {
+	for _, c := range []prometheus.Collector{counterClientsCreated, counterClientsDeleted} {
+		if err := r.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // NewAPI returns a server which implements the gRPC API interface.
 func NewAPI(s storage.Storage, logger logrus.FieldLogger) api.DexServer {
+	return NewAPIWithConfig(s, logger, APIConfig{})
+}
+
+// APIConfig holds optional settings for the gRPC API. The zero value logs
+// plain client IDs and allows any client.
+type APIConfig struct {
+	// If non-empty, client IDs are logged hashed with this salt. See
+	// HashClientIDForLog.
+	ClientIDLogSalt string
+
+	// Clients created through the API are checked against the policy.
+	ClientPolicy ClientPolicy
+}
+
+// NewAPIWithConfig returns a server which implements the gRPC API interface,
+// configured by c.
+func NewAPIWithConfig(s storage.Storage, logger logrus.FieldLogger, c APIConfig) api.DexServer {
 	return dexAPI{
-		s:      s,
-		logger: logger,
+		s:               s,
+		logger:          logger,
+		clientIDLogSalt: c.ClientIDLogSalt,
+		policy:          c.ClientPolicy,
 	}
 }
 
 type dexAPI struct {
-	s      storage.Storage
-	logger logrus.FieldLogger
+	s               storage.Storage
+	logger          logrus.FieldLogger
+	clientIDLogSalt string
+	policy          ClientPolicy
 }
 
 func (d dexAPI) CreateClient(ctx context.Context, req *api.CreateClientReq) (*api.CreateClientResp, error) {
@@ -50,6 +105,22 @@ func (d dexAPI) CreateClient(ctx context.Context, req *api.CreateClientReq) (*ap
 		return nil, errors.New("no client supplied")
 	}
 
+	for _, uri := range req.Client.RedirectUris {
+		if err := d.policy.ValidateRedirectURI(uri); err != nil {
+			counterClientsCreated.WithLabelValues(outcomeInvalid).Inc()
+			return nil, status.Errorf(codes.InvalidArgument, "invalid redirect URI: %v", err)
+		}
+	}
+
+	// Secrets generated below are strong enough, only check ones supplied by the
+	// caller.
+	if req.Client.Secret != "" && !req.Client.Public {
+		if err := d.policy.ValidateSecret(req.Client.Secret); err != nil {
+			counterClientsCreated.WithLabelValues(outcomeInvalid).Inc()
+			return nil, status.Errorf(codes.InvalidArgument, "invalid client secret: %v", err)
+		}
+	}
+
 	if req.Client.Id == "" {
 		req.Client.Id = storage.NewID()
 	}
@@ -68,11 +139,14 @@ func (d dexAPI) CreateClient(ctx context.Context, req *api.CreateClientReq) (*ap
 	}
 	if err := d.s.CreateClient(c); err != nil {
 		if err == storage.ErrAlreadyExists {
+			counterClientsCreated.WithLabelValues(outcomeAlreadyExists).Inc()
 			return &api.CreateClientResp{AlreadyExists: true}, nil
 		}
+		counterClientsCreated.WithLabelValues(outcomeError).Inc()
 		d.logger.Errorf("api: failed to create client: %v", err)
 		return nil, fmt.Errorf("create client: %v", err)
 	}
+	counterClientsCreated.WithLabelValues(outcomeSuccess).Inc()
 
 	return &api.CreateClientResp{
 		Client: req.Client,
@@ -83,11 +157,14 @@ func (d dexAPI) DeleteClient(ctx context.Context, req *api.DeleteClientReq) (*ap
 	err := d.s.DeleteClient(req.Id)
 	if err != nil {
 		if err == storage.ErrNotFound {
+			counterClientsDeleted.WithLabelValues(outcomeNotFound).Inc()
 			return &api.DeleteClientResp{NotFound: true}, nil
 		}
+		counterClientsDeleted.WithLabelValues(outcomeError).Inc()
 		d.logger.Errorf("api: failed to delete client: %v", err)
 		return nil, fmt.Errorf("delete client: %v", err)
 	}
+	counterClientsDeleted.WithLabelValues(outcomeSuccess).Inc()
 	return &api.DeleteClientResp{}, nil
 }
 
@@ -273,7 +350,7 @@ func (d dexAPI) RevokeRefresh(ctx context.Context, req *api.RevokeRefreshReq) (*
 	updater := func(old storage.OfflineSessions) (storage.OfflineSessions, error) {
 		refreshRef := old.Refresh[req.ClientId]
 		if refreshRef == nil || refreshRef.ID == "" {
-			d.logger.Errorf("api: refresh token issued to client %q for user %q not found for deletion", req.ClientId, id.UserId)
+			d.logger.Errorf("api: refresh token issued to client %q for user %q not found for deletion", clientIDForLog(d.clientIDLogSalt, req.ClientId), id.UserId)
 			notFound = true
 			return old, storage.ErrNotFound
 		}
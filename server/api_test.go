@@ -2,8 +2,10 @@ package server
 
 import (
 	"context"
+	"errors"
 	"net"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -11,8 +13,12 @@ import (
 	"github.com/coreos/dex/server/internal"
 	"github.com/coreos/dex/storage"
 	"github.com/coreos/dex/storage/memory"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // apiClient is a test gRPC client. When constructed, it runs a server in
@@ -54,6 +60,174 @@ func newAPI(s storage.Storage, logger logrus.FieldLogger, t *testing.T) *apiClie
 	}
 }
 
+func counterValue(t *testing.T, c *prometheus.CounterVec, outcome string) float64 {
+	var m dto.Metric
+	if err := c.WithLabelValues(outcome).Write(&m); err != nil {
+		t.Fatalf("read counter: %v", err)
+	}
+	return m.GetCounter().GetValue()
+}
+
+// Attempts to create and delete a test Client, checking the churn metrics.
+func TestClientMetrics(t *testing.T) {
+	logger := &logrus.Logger{
+		Out:       os.Stderr,
+		Formatter: &logrus.TextFormatter{DisableColors: true},
+		Level:     logrus.DebugLevel,
+	}
+
+	s := memory.New(logger)
+	client := newAPI(s, logger, t)
+	defer client.Close()
+
+	ctx := context.Background()
+
+	created := counterValue(t, counterClientsCreated, outcomeSuccess)
+	duplicates := counterValue(t, counterClientsCreated, outcomeAlreadyExists)
+	deleted := counterValue(t, counterClientsDeleted, outcomeSuccess)
+	notFound := counterValue(t, counterClientsDeleted, outcomeNotFound)
+
+	createReq := api.CreateClientReq{
+		Client: &api.Client{
+			Id:           "test",
+			RedirectUris: []string{"https://example.com/callback"},
+		},
+	}
+	if _, err := client.CreateClient(ctx, &createReq); err != nil {
+		t.Fatalf("Unable to create client: %v", err)
+	}
+	if resp, err := client.CreateClient(ctx, &createReq); err != nil || !resp.AlreadyExists {
+		t.Fatalf("Expected duplicate client to already exist: %v", err)
+	}
+
+	deleteReq := api.DeleteClientReq{Id: "test"}
+	if _, err := client.DeleteClient(ctx, &deleteReq); err != nil {
+		t.Fatalf("Unable to delete client: %v", err)
+	}
+	if resp, err := client.DeleteClient(ctx, &deleteReq); err != nil || !resp.NotFound {
+		t.Fatalf("Expected deleted client to not be found: %v", err)
+	}
+
+	checks := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"created", counterValue(t, counterClientsCreated, outcomeSuccess), created + 1},
+		{"already exists", counterValue(t, counterClientsCreated, outcomeAlreadyExists), duplicates + 1},
+		{"deleted", counterValue(t, counterClientsDeleted, outcomeSuccess), deleted + 1},
+		{"not found", counterValue(t, counterClientsDeleted, outcomeNotFound), notFound + 1},
+	}
+	for _, check := range checks {
+		if check.got != check.want {
+			t.Errorf("%s: expected counter %v, got %v", check.name, check.want, check.got)
+		}
+	}
+}
+
+// Ensures clients created through the API are checked against the policy.
+func TestCreateClientPolicy(t *testing.T) {
+	logger := &logrus.Logger{
+		Out:       os.Stderr,
+		Formatter: &logrus.TextFormatter{DisableColors: true},
+		Level:     logrus.DebugLevel,
+	}
+
+	policy := ClientPolicy{RequireHTTPSRedirects: true, MinSecretLength: 16}
+	tests := []struct {
+		name    string
+		client  api.Client
+		wantErr bool
+	}{
+		{
+			name:   "generated secret",
+			client: api.Client{Id: "generated"},
+		},
+		{
+			name:   "long secret",
+			client: api.Client{Id: "long", Secret: "0123456789abcdef"},
+		},
+		{
+			name:    "short secret",
+			client:  api.Client{Id: "short", Secret: "secret"},
+			wantErr: true,
+		},
+		{
+			name:   "https redirect URI",
+			client: api.Client{Id: "https", RedirectUris: []string{"https://example.com/callback"}},
+		},
+		{
+			name:    "http redirect URI",
+			client:  api.Client{Id: "http", RedirectUris: []string{"http://example.com/callback"}},
+			wantErr: true,
+		},
+		{
+			name:   "public client",
+			client: api.Client{Id: "public", Secret: "secret", Public: true},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range tests {
+		s := memory.New(logger)
+		client := tc.client
+		_, err := NewAPIWithConfig(s, logger, APIConfig{ClientPolicy: policy}).CreateClient(ctx, &api.CreateClientReq{Client: &client})
+		if tc.wantErr {
+			if st, ok := status.FromError(err); !ok || st.Code() != codes.InvalidArgument {
+				t.Errorf("%s: expected InvalidArgument, got %v", tc.name, err)
+			}
+			if _, err := s.GetClient(tc.client.Id); err != storage.ErrNotFound {
+				t.Errorf("%s: expected rejected client not to be stored, got %v", tc.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: create client: %v", tc.name, err)
+		}
+	}
+}
+
+// Ensures the secret validator only sees secrets supplied by the caller.
+func TestCreateClientSecretValidator(t *testing.T) {
+	logger := &logrus.Logger{
+		Out:       os.Stderr,
+		Formatter: &logrus.TextFormatter{DisableColors: true},
+		Level:     logrus.DebugLevel,
+	}
+
+	var validated []string
+	policy := ClientPolicy{
+		SecretValidator: func(secret string) error {
+			validated = append(validated, secret)
+			if secret == "breached" {
+				return errors.New("secret appears in a breach")
+			}
+			return nil
+		},
+	}
+	s := memory.New(logger)
+	serv := NewAPIWithConfig(s, logger, APIConfig{ClientPolicy: policy})
+	ctx := context.Background()
+
+	if _, err := serv.CreateClient(ctx, &api.CreateClientReq{Client: &api.Client{Id: "generated"}}); err != nil {
+		t.Fatalf("create client with generated secret: %v", err)
+	}
+	if len(validated) != 0 {
+		t.Errorf("expected generated secret not to be validated, validated %q", validated)
+	}
+
+	if _, err := serv.CreateClient(ctx, &api.CreateClientReq{Client: &api.Client{Id: "supplied", Secret: "supplied-secret"}}); err != nil {
+		t.Fatalf("create client with supplied secret: %v", err)
+	}
+	_, err := serv.CreateClient(ctx, &api.CreateClientReq{Client: &api.Client{Id: "breached", Secret: "breached"}})
+	if st, ok := status.FromError(err); !ok || st.Code() != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a breached secret, got %v", err)
+	}
+	if want := []string{"supplied-secret", "breached"}; !reflect.DeepEqual(validated, want) {
+		t.Errorf("expected validated secrets %q, got %q", want, validated)
+	}
+}
+
 // Attempts to create, update and delete a test Password
 func TestPassword(t *testing.T) {
 	logger := &logrus.Logger{
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/coreos/dex/storage"
+)
+
+// Rules reported by AuditClients.
+const (
+	RuleHTTPSRedirects  = "https_redirects"
+	RuleSecretRequired  = "secret_required"
+	RuleMinSecretLength = "min_secret_length"
+	RuleSecretValidator = "secret_validator"
+)
+
+// ClientPolicy describes properties every client is expected to have. Each rule
+// is only checked when enabled.
+type ClientPolicy struct {
+	// Redirect URIs must use "https", except for loopback addresses.
+	RequireHTTPSRedirects bool
+
+	// Additional schemes redirect URIs may use, such as the private-use schemes
+	// of native apps. Setting any implies RequireHTTPSRedirects.
+	AllowedRedirectSchemes []string
+
+	// Clients which aren't public must have a secret.
+	RequireSecrets bool
+
+	// If non-zero, the minimum length of the secret of clients which aren't public.
+	MinSecretLength int
+
+	// Optional check run on secrets which pass the rules above, such as an
+	// entropy check or a lookup in a list of breached passwords. The gRPC API
+	// only runs it on secrets supplied by the caller, not ones it generates.
+	SecretValidator func(secret string) error
+}
+
+// ClientViolation is a client which doesn't meet a rule of a ClientPolicy.
+type ClientViolation struct {
+	ClientID string
+	Rule     string
+	Detail   string
+}
+
+// AuditClients checks every client in the storage against the policy and returns
+// the violations found, if any.
+func AuditClients(s storage.Storage, policy ClientPolicy) ([]ClientViolation, error) {
+	clients, err := s.ListClients()
+	if err != nil {
+		return nil, fmt.Errorf("list clients: %v", err)
+	}
+
+	var violations []ClientViolation
+	for _, client := range clients {
+		if policy.checksRedirectURIs() {
+			for _, uri := range client.RedirectURIs {
+				if err := policy.ValidateRedirectURI(uri); err != nil {
+					violations = append(violations, ClientViolation{
+						ClientID: client.ID,
+						Rule:     RuleHTTPSRedirects,
+						Detail:   err.Error(),
+					})
+				}
+			}
+		}
+
+		if client.Public {
+			continue
+		}
+		if rule, detail := policy.checkSecret(client.Secret); rule != "" {
+			violations = append(violations, ClientViolation{
+				ClientID: client.ID,
+				Rule:     rule,
+				Detail:   detail,
+			})
+		}
+	}
+	return violations, nil
+}
+
+// ValidateRedirectURI returns an error naming the redirect URI if the policy
+// doesn't allow its scheme.
+func (p ClientPolicy) ValidateRedirectURI(uri string) error {
+	if !p.checksRedirectURIs() || isSecureRedirectURI(uri) {
+		return nil
+	}
+	if u, err := url.Parse(uri); err == nil {
+		for _, scheme := range p.AllowedRedirectSchemes {
+			if strings.EqualFold(u.Scheme, scheme) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("redirect URI %q does not use https or an allowed scheme", uri)
+}
+
+// checksRedirectURIs reports if the policy restricts the schemes of redirect URIs.
+func (p ClientPolicy) checksRedirectURIs() bool {
+	return p.RequireHTTPSRedirects || len(p.AllowedRedirectSchemes) > 0
+}
+
+// ValidateSecret returns an error describing why the secret of a client which
+// isn't public doesn't meet the policy, if it doesn't. Provisioning tools can use
+// it to check secrets before creating clients.
+func (p ClientPolicy) ValidateSecret(secret string) error {
+	if rule, detail := p.checkSecret(secret); rule != "" {
+		return fmt.Errorf("%s: %s", rule, detail)
+	}
+	return nil
+}
+
+// checkSecret returns the rule the secret violates and a description, or an
+// empty rule if it meets the policy.
+func (p ClientPolicy) checkSecret(secret string) (rule, detail string) {
+	n := len(secret)
+	switch {
+	case n == 0 && p.RequireSecrets:
+		return RuleSecretRequired, "client is not public and has no secret"
+	case n > 0 && p.MinSecretLength > 0 && n < p.MinSecretLength:
+		return RuleMinSecretLength, fmt.Sprintf("secret is %d characters, policy requires %d", n, p.MinSecretLength)
+	}
+	if n > 0 && p.SecretValidator != nil {
+		if err := p.SecretValidator(secret); err != nil {
+			return RuleSecretValidator, err.Error()
+		}
+	}
+	return "", ""
+}
+
+// ClientStats summarizes the clients in a storage.
+type ClientStats struct {
+	Clients          int
+	PublicClients    int
+	WithLogoURL      int
+	WithTrustedPeers int
+
+	RedirectURIs int
+	// Largest number of redirect URIs registered by one client.
+	MaxRedirectURIs int
+}
+
+// AverageRedirectURIs returns the mean number of redirect URIs per client.
+func (s ClientStats) AverageRedirectURIs() float64 {
+	if s.Clients == 0 {
+		return 0
+	}
+	return float64(s.RedirectURIs) / float64(s.Clients)
+}
+
+// GetClientStats computes ClientStats over every client in the storage.
+func GetClientStats(s storage.Storage) (ClientStats, error) {
+	clients, err := s.ListClients()
+	if err != nil {
+		return ClientStats{}, fmt.Errorf("list clients: %v", err)
+	}
+
+	var stats ClientStats
+	for _, client := range clients {
+		stats.Clients++
+		if client.Public {
+			stats.PublicClients++
+		}
+		if client.LogoURL != "" {
+			stats.WithLogoURL++
+		}
+		if len(client.TrustedPeers) > 0 {
+			stats.WithTrustedPeers++
+		}
+		n := len(client.RedirectURIs)
+		stats.RedirectURIs += n
+		if n > stats.MaxRedirectURIs {
+			stats.MaxRedirectURIs = n
+		}
+	}
+	return stats, nil
+}
+
+// RedirectConflict is a redirect URI registered by more than one client.
+type RedirectConflict struct {
+	RedirectURI string
+	ClientIDs   []string
+}
+
+// RedirectURIConflicts scans every client in the storage and reports redirect
+// URIs registered by more than one of them, which would let one client receive
+// another's authorization codes. Conflicts are sorted by URI, and their client
+// IDs are sorted too.
+func RedirectURIConflicts(s storage.Storage) ([]RedirectConflict, error) {
+	clients, err := s.ListClients()
+	if err != nil {
+		return nil, fmt.Errorf("list clients: %v", err)
+	}
+
+	owners := make(map[string][]string)
+	for _, client := range clients {
+		seen := make(map[string]bool)
+		for _, uri := range client.RedirectURIs {
+			// Public clients may all use the out-of-band URI.
+			if uri == redirectURIOOB || seen[uri] {
+				continue
+			}
+			seen[uri] = true
+			owners[uri] = append(owners[uri], client.ID)
+		}
+	}
+
+	var conflicts []RedirectConflict
+	for uri, ids := range owners {
+		if len(ids) < 2 {
+			continue
+		}
+		sort.Strings(ids)
+		conflicts = append(conflicts, RedirectConflict{RedirectURI: uri, ClientIDs: ids})
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].RedirectURI < conflicts[j].RedirectURI
+	})
+	return conflicts, nil
+}
+
+// ConnectorConflict is a singleton connector type, such as the local password
+// connector, which more than one connector uses.
+type ConnectorConflict struct {
+	Type         string
+	ConnectorIDs []string
+}
+
+// SingletonConnectorConflicts scans every connector in the storage and reports
+// connector types which may only be configured once but are used by more than
+// one connector. Conflicts are sorted by type, and their connector IDs are
+// sorted too.
+func SingletonConnectorConflicts(s storage.Storage) ([]ConnectorConflict, error) {
+	conns, err := s.ListConnectors()
+	if err != nil {
+		return nil, fmt.Errorf("list connectors: %v", err)
+	}
+
+	conflicts := singletonConflicts(conns)
+	for _, c := range conflicts {
+		sort.Strings(c.ConnectorIDs)
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Type < conflicts[j].Type
+	})
+	return conflicts, nil
+}
+
+// isSecureRedirectURI reports if the URI uses https or points at the loopback
+// interface, which native clients commonly use with plain http.
+func isSecureRedirectURI(uri string) bool {
+	if uri == redirectURIOOB {
+		return true
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "https" {
+		return true
+	}
+	if u.Scheme != "http" {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
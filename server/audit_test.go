@@ -0,0 +1,331 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+
+	"github.com/coreos/dex/storage"
+	"github.com/coreos/dex/storage/memory"
+)
+
+func TestAuditClients(t *testing.T) {
+	s := memory.New(logger)
+	clients := []storage.Client{
+		{
+			ID:           "good",
+			Secret:       "a-long-enough-secret",
+			RedirectURIs: []string{"https://example.com/callback", "http://127.0.0.1:5555/callback"},
+		},
+		{
+			ID:           "insecure",
+			Secret:       "a-long-enough-secret",
+			RedirectURIs: []string{"http://example.com/callback"},
+		},
+		{
+			ID:           "no-secret",
+			RedirectURIs: []string{"https://example.com/callback"},
+		},
+		{
+			ID:           "short-secret",
+			Secret:       "short",
+			RedirectURIs: []string{"https://example.com/callback"},
+		},
+		{
+			ID:     "public",
+			Public: true,
+		},
+	}
+	for _, c := range clients {
+		if err := s.CreateClient(c); err != nil {
+			t.Fatalf("create client: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		policy ClientPolicy
+		want   []string // "clientID rule"
+	}{
+		{
+			name: "empty policy",
+		},
+		{
+			name:   "https redirects",
+			policy: ClientPolicy{RequireHTTPSRedirects: true},
+			want:   []string{"insecure " + RuleHTTPSRedirects},
+		},
+		{
+			name:   "secrets",
+			policy: ClientPolicy{RequireSecrets: true, MinSecretLength: 10},
+			want: []string{
+				"no-secret " + RuleSecretRequired,
+				"short-secret " + RuleMinSecretLength,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		violations, err := AuditClients(s, tc.policy)
+		if err != nil {
+			t.Errorf("%s: audit clients: %v", tc.name, err)
+			continue
+		}
+		got := make(map[string]bool)
+		for _, v := range violations {
+			got[v.ClientID+" "+v.Rule] = true
+		}
+		want := make(map[string]bool)
+		for _, w := range tc.want {
+			want[w] = true
+		}
+		if diff := pretty.Compare(want, got); diff != "" {
+			t.Errorf("%s: got!=want: %s", tc.name, diff)
+		}
+	}
+}
+
+// rejectSecret returns a secret validator rejecting the given secret.
+func rejectSecret(rejected string) func(string) error {
+	return func(secret string) error {
+		if secret == rejected {
+			return errors.New("secret is too common")
+		}
+		return nil
+	}
+}
+
+func TestClientPolicyValidateSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ClientPolicy
+		secret  string
+		wantErr bool
+	}{
+		{
+			name: "empty policy",
+		},
+		{
+			name:    "missing secret",
+			policy:  ClientPolicy{RequireSecrets: true},
+			wantErr: true,
+		},
+		{
+			name:    "short secret",
+			policy:  ClientPolicy{MinSecretLength: 10},
+			secret:  "short",
+			wantErr: true,
+		},
+		{
+			name:   "long enough secret",
+			policy: ClientPolicy{RequireSecrets: true, MinSecretLength: 10},
+			secret: "a-long-enough-secret",
+		},
+		{
+			name:    "rejected by validator",
+			policy:  ClientPolicy{SecretValidator: rejectSecret("password")},
+			secret:  "password",
+			wantErr: true,
+		},
+		{
+			name:   "accepted by validator",
+			policy: ClientPolicy{SecretValidator: rejectSecret("password")},
+			secret: "a-long-enough-secret",
+		},
+		{
+			name:   "validator skipped without secret",
+			policy: ClientPolicy{SecretValidator: rejectSecret("")},
+		},
+	}
+
+	for _, tc := range tests {
+		err := tc.policy.ValidateSecret(tc.secret)
+		if err != nil && !tc.wantErr {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+		if err == nil && tc.wantErr {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
+
+func TestRedirectURIConflicts(t *testing.T) {
+	s := memory.New(logger)
+	clients := []storage.Client{
+		{
+			ID:           "foo",
+			RedirectURIs: []string{"https://example.com/callback", "https://foo.example.com/callback"},
+		},
+		{
+			ID:           "bar",
+			RedirectURIs: []string{"https://example.com/callback", "https://example.com/callback"},
+		},
+		{
+			ID:           "baz",
+			RedirectURIs: []string{"https://example.com/callback", redirectURIOOB},
+		},
+		{
+			ID:           "public",
+			Public:       true,
+			RedirectURIs: []string{redirectURIOOB},
+		},
+	}
+	for _, c := range clients {
+		if err := s.CreateClient(c); err != nil {
+			t.Fatalf("create client: %v", err)
+		}
+	}
+
+	got, err := RedirectURIConflicts(s)
+	if err != nil {
+		t.Fatalf("redirect URI conflicts: %v", err)
+	}
+	want := []RedirectConflict{
+		{
+			RedirectURI: "https://example.com/callback",
+			ClientIDs:   []string{"bar", "baz", "foo"},
+		},
+	}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("got!=want: %s", diff)
+	}
+}
+
+func TestSingletonConnectorConflicts(t *testing.T) {
+	s := memory.New(logger)
+	conns := []storage.Connector{
+		{ID: "passwords", Type: LocalConnector},
+		{ID: "mock1", Type: "mockCallback"},
+		{ID: "mock2", Type: "mockCallback"},
+		{ID: LocalConnector, Type: LocalConnector},
+	}
+	for _, c := range conns {
+		if err := s.CreateConnector(c); err != nil {
+			t.Fatalf("create connector: %v", err)
+		}
+	}
+
+	got, err := SingletonConnectorConflicts(s)
+	if err != nil {
+		t.Fatalf("singleton connector conflicts: %v", err)
+	}
+	want := []ConnectorConflict{
+		{
+			Type:         LocalConnector,
+			ConnectorIDs: []string{LocalConnector, "passwords"},
+		},
+	}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("got!=want: %s", diff)
+	}
+}
+
+func TestGetClientStats(t *testing.T) {
+	s := memory.New(logger)
+	clients := []storage.Client{
+		{
+			ID:           "foo",
+			RedirectURIs: []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"},
+			LogoURL:      "https://example.com/logo.png",
+		},
+		{
+			ID:           "bar",
+			RedirectURIs: []string{"https://example.com/callback"},
+			TrustedPeers: []string{"foo"},
+		},
+		{
+			ID:     "public",
+			Public: true,
+		},
+	}
+	for _, c := range clients {
+		if err := s.CreateClient(c); err != nil {
+			t.Fatalf("create client: %v", err)
+		}
+	}
+
+	got, err := GetClientStats(s)
+	if err != nil {
+		t.Fatalf("get client stats: %v", err)
+	}
+	want := ClientStats{
+		Clients:          3,
+		PublicClients:    1,
+		WithLogoURL:      1,
+		WithTrustedPeers: 1,
+		RedirectURIs:     4,
+		MaxRedirectURIs:  3,
+	}
+	if diff := pretty.Compare(want, got); diff != "" {
+		t.Errorf("got!=want: %s", diff)
+	}
+	if avg := got.AverageRedirectURIs(); avg < 1.33 || avg > 1.34 {
+		t.Errorf("expected an average of 4/3 redirect URIs, got %f", avg)
+	}
+}
+
+func TestClientPolicyValidateRedirectURI(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ClientPolicy
+		uri     string
+		wantErr bool
+	}{
+		{
+			name: "empty policy",
+			uri:  "http://example.com/callback",
+		},
+		{
+			name:   "https",
+			policy: ClientPolicy{RequireHTTPSRedirects: true},
+			uri:    "https://example.com/callback",
+		},
+		{
+			name:   "http on localhost",
+			policy: ClientPolicy{RequireHTTPSRedirects: true},
+			uri:    "http://localhost:5555/callback",
+		},
+		{
+			name:    "http",
+			policy:  ClientPolicy{RequireHTTPSRedirects: true},
+			uri:     "http://example.com/callback",
+			wantErr: true,
+		},
+		{
+			name:    "custom scheme",
+			policy:  ClientPolicy{RequireHTTPSRedirects: true},
+			uri:     "com.example.app:/callback",
+			wantErr: true,
+		},
+		{
+			name: "allowed custom scheme",
+			policy: ClientPolicy{
+				RequireHTTPSRedirects:  true,
+				AllowedRedirectSchemes: []string{"com.example.app"},
+			},
+			uri: "com.example.app:/callback",
+		},
+		{
+			name:   "allowed schemes alone",
+			policy: ClientPolicy{AllowedRedirectSchemes: []string{"com.example.app"}},
+			uri:    "com.example.app:/callback",
+		},
+		{
+			name:    "allowed schemes imply https",
+			policy:  ClientPolicy{AllowedRedirectSchemes: []string{"com.example.app"}},
+			uri:     "http://example.com/callback",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		err := tc.policy.ValidateRedirectURI(tc.uri)
+		if err != nil && !tc.wantErr {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+		if err == nil && tc.wantErr {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
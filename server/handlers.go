@@ -140,7 +140,7 @@ func (s *Server) discoveryHandler() (http.HandlerFunc, error) {
 func (s *Server) handleAuthorization(w http.ResponseWriter, r *http.Request) {
 	authReq, err := s.parseAuthorizationRequest(r)
 	if err != nil {
-		s.logger.Errorf("Failed to parse authorization request: %v", err)
+		s.logger.Errorf("Failed to parse authorization request: %s", err.logString())
 		if handler, ok := err.Handle(); ok {
 			// client_id and redirect_uri checked out and we can redirect back to
 			// the client with the error.
@@ -453,7 +453,7 @@ func (s *Server) handleApproval(w http.ResponseWriter, r *http.Request) {
 		}
 		client, err := s.storage.GetClient(authReq.ClientID)
 		if err != nil {
-			s.logger.Errorf("Failed to get client %q: %v", authReq.ClientID, err)
+			s.logger.Errorf("Failed to get client %q: %v", s.logClientID(authReq.ClientID), err)
 			s.renderError(w, http.StatusInternalServerError, "Failed to retrieve client.")
 			return
 		}
@@ -830,7 +830,7 @@ func (s *Server) handleRefreshToken(w http.ResponseWriter, r *http.Request, clie
 		return
 	}
 	if refresh.ClientID != client.ID {
-		s.logger.Errorf("client %s trying to claim token for client %s", client.ID, refresh.ClientID)
+		s.logger.Errorf("client %s trying to claim token for client %s", s.logClientID(client.ID), s.logClientID(refresh.ClientID))
 		s.tokenErrHelper(w, errInvalidRequest, "Refresh token is invalid or has already been claimed by another client.", http.StatusBadRequest)
 		return
 	}
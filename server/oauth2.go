@@ -35,6 +35,11 @@ type authErr struct {
 	RedirectURI string
 	Type        string
 	Description string
+
+	// logDescription, if set, replaces Description in log output. It's used
+	// when the description contains client IDs which must be hashed before
+	// they're logged.
+	logDescription string
 }
 
 func (err *authErr) Status() int {
@@ -48,6 +53,14 @@ func (err *authErr) Error() string {
 	return err.Description
 }
 
+// logString returns the form of the error which should appear in log output.
+func (err *authErr) logString() string {
+	if err.logDescription != "" {
+		return err.logDescription
+	}
+	return err.Description
+}
+
 func (err *authErr) Handle() (http.Handler, bool) {
 	// Didn't get a valid redirect URI.
 	if err.RedirectURI == "" {
@@ -339,7 +352,7 @@ func (s *Server) newIDToken(clientID string, claims storage.Claims, scopes []str
 			}
 			if !isTrusted {
 				// TODO(ericchiang): propagate this error to the client.
-				return "", expiry, fmt.Errorf("peer (%s) does not trust client", peerID)
+				return "", expiry, fmt.Errorf("peer (%s) does not trust client", s.logClientID(peerID))
 			}
 			tok.Audience = append(tok.Audience, peerID)
 		}
@@ -374,12 +387,12 @@ func (s *Server) newIDToken(clientID string, claims storage.Claims, scopes []str
 // parse the initial request from the OAuth2 client.
 func (s *Server) parseAuthorizationRequest(r *http.Request) (req storage.AuthRequest, oauth2Err *authErr) {
 	if err := r.ParseForm(); err != nil {
-		return req, &authErr{"", "", errInvalidRequest, "Failed to parse request body."}
+		return req, &authErr{"", "", errInvalidRequest, "Failed to parse request body.", ""}
 	}
 	q := r.Form
 	redirectURI, err := url.QueryUnescape(q.Get("redirect_uri"))
 	if err != nil {
-		return req, &authErr{"", "", errInvalidRequest, "No redirect_uri provided."}
+		return req, &authErr{"", "", errInvalidRequest, "No redirect_uri provided.", ""}
 	}
 
 	clientID := q.Get("client_id")
@@ -393,20 +406,21 @@ func (s *Server) parseAuthorizationRequest(r *http.Request) (req storage.AuthReq
 	if err != nil {
 		if err == storage.ErrNotFound {
 			description := fmt.Sprintf("Invalid client_id (%q).", clientID)
-			return req, &authErr{"", "", errUnauthorizedClient, description}
+			logDescription := fmt.Sprintf("Invalid client_id (%q).", s.logClientID(clientID))
+			return req, &authErr{"", "", errUnauthorizedClient, description, logDescription}
 		}
 		s.logger.Errorf("Failed to get client: %v", err)
-		return req, &authErr{"", "", errServerError, ""}
+		return req, &authErr{"", "", errServerError, "", ""}
 	}
 
 	if !validateRedirectURI(client, redirectURI) {
 		description := fmt.Sprintf("Unregistered redirect_uri (%q).", redirectURI)
-		return req, &authErr{"", "", errInvalidRequest, description}
+		return req, &authErr{"", "", errInvalidRequest, description, ""}
 	}
 
 	// From here on out, we want to redirect back to the client with an error.
 	newErr := func(typ, format string, a ...interface{}) *authErr {
-		return &authErr{state, redirectURI, typ, fmt.Sprintf(format, a...)}
+		return &authErr{state, redirectURI, typ, fmt.Sprintf(format, a...), ""}
 	}
 
 	var (
@@ -442,7 +456,16 @@ func (s *Server) parseAuthorizationRequest(r *http.Request) (req storage.AuthReq
 		return req, newErr("invalid_scope", "Unrecognized scope(s) %q", unrecognized)
 	}
 	if len(invalidScopes) > 0 {
-		return req, newErr("invalid_scope", "Client can't request scope(s) %q", invalidScopes)
+		scopeErr := newErr("invalid_scope", "Client can't request scope(s) %q", invalidScopes)
+		if s.clientIDLogSalt != "" {
+			logScopes := make([]string, len(invalidScopes))
+			for i, scope := range invalidScopes {
+				peerID, _ := parseCrossClientScope(scope)
+				logScopes[i] = scopeCrossClientPrefix + s.logClientID(peerID)
+			}
+			scopeErr.logDescription = fmt.Sprintf("Client can't request scope(s) %q", logScopes)
+		}
+		return req, scopeErr
 	}
 
 	var rt struct {
@@ -1,7 +1,11 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -39,6 +43,50 @@ import (
 // connector maintained by the server.
 const LocalConnector = "local"
 
+// singletonConnectorTypes are connector types which may be configured at most
+// once. Two local connectors would show the same password DB twice, with users
+// getting a different subject depending on which one they picked.
+var singletonConnectorTypes = map[string]bool{
+	LocalConnector: true,
+}
+
+// checkSingletonConnectors returns an error listing every singleton connector
+// type which is configured more than once.
+func checkSingletonConnectors(conns []storage.Connector) error {
+	var dups []string
+	for _, c := range singletonConflicts(conns) {
+		dups = append(dups, fmt.Sprintf("%q (%s)", c.Type, strings.Join(c.ConnectorIDs, ", ")))
+	}
+	if len(dups) > 0 {
+		return fmt.Errorf("connector types may only be configured once: %s", strings.Join(dups, "; "))
+	}
+	return nil
+}
+
+// singletonConflicts returns the singleton connector types which appear more
+// than once in the list, in the order they first appear.
+func singletonConflicts(conns []storage.Connector) []ConnectorConflict {
+	ids := make(map[string][]string)
+	var types []string
+	for _, conn := range conns {
+		if !singletonConnectorTypes[conn.Type] {
+			continue
+		}
+		if len(ids[conn.Type]) == 0 {
+			types = append(types, conn.Type)
+		}
+		ids[conn.Type] = append(ids[conn.Type], conn.ID)
+	}
+
+	var conflicts []ConnectorConflict
+	for _, t := range types {
+		if len(ids[t]) > 1 {
+			conflicts = append(conflicts, ConnectorConflict{Type: t, ConnectorIDs: ids[t]})
+		}
+	}
+	return conflicts
+}
+
 // Connector is a connector with resource version metadata.
 type Connector struct {
 	ResourceVersion string
@@ -79,6 +127,14 @@ type Config struct {
 
 	Logger logrus.FieldLogger
 
+	// If specified, client IDs are logged as a hash salted with this value rather
+	// than in plain text. See HashClientIDForLog.
+	ClientIDLogSalt string
+
+	// Policy clients created through the gRPC API are checked against. See NewAPI.
+	// The zero value allows any client.
+	ClientPolicy ClientPolicy
+
 	PrometheusRegistry *prometheus.Registry
 }
 
@@ -139,6 +195,8 @@ type Server struct {
 	idTokensValidFor time.Duration
 
 	logger logrus.FieldLogger
+
+	clientIDLogSalt string
 }
 
 // NewServer constructs a server from the provided config.
@@ -200,6 +258,7 @@ func newServer(ctx context.Context, c Config, rotationStrategy rotationStrategy)
 		now:                    now,
 		templates:              tmpls,
 		logger:                 c.Logger,
+		clientIDLogSalt:        c.ClientIDLogSalt,
 	}
 
 	// Retrieves connector objects in backend storage. This list includes the static connectors
@@ -212,6 +271,9 @@ func newServer(ctx context.Context, c Config, rotationStrategy rotationStrategy)
 	if len(storageConnectors) == 0 && len(s.connectors) == 0 {
 		return nil, errors.New("server: no connectors specified")
 	}
+	if err := checkSingletonConnectors(storageConnectors); err != nil {
+		return nil, fmt.Errorf("server: %v", err)
+	}
 
 	for _, conn := range storageConnectors {
 		if _, err := s.OpenConnector(conn); err != nil {
@@ -307,6 +369,29 @@ func (s *Server) absURL(pathItems ...string) string {
 	return u.String()
 }
 
+// HashClientIDForLog returns a stable, salted hash of a client ID. Operators can
+// use it to correlate log lines with a client without the ID itself appearing in
+// the logs.
+func HashClientIDForLog(salt, id string) string {
+	h := hmac.New(sha256.New, []byte(salt))
+	h.Write([]byte(id))
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// logClientID returns the form of a client ID which should appear in log output.
+func (s *Server) logClientID(id string) string {
+	return clientIDForLog(s.clientIDLogSalt, id)
+}
+
+// clientIDForLog returns the client ID hashed with the salt, or the ID itself if
+// no salt is configured.
+func clientIDForLog(salt, id string) string {
+	if salt == "" {
+		return id
+	}
+	return HashClientIDForLog(salt, id)
+}
+
 func newPasswordDB(s storage.Storage) interface {
 	connector.Connector
 	connector.PasswordConnector
@@ -468,8 +553,111 @@ func openConnector(logger logrus.FieldLogger, conn storage.Connector) (connector
 	return c, nil
 }
 
+// RedactedSecret is the placeholder used in place of secret connector config
+// values by RedactConnector.
+const RedactedSecret = "******"
+
+// connectorSecretFields are the lower cased JSON keys of connector config fields
+// which hold secrets, such as OAuth2 client secrets and LDAP bind passwords. Keys
+// are compared lower cased because encoding/json matches field names without
+// regard to case.
+var connectorSecretFields = map[string]bool{
+	"clientsecret": true,
+	"bindpw":       true,
+	"password":     true,
+}
+
+// RedactConnector returns a copy of the connector with known secret fields in
+// its config replaced by RedactedSecret, so it can be safely displayed to an
+// admin. Secret fields are redacted at any depth, including in nested objects
+// and lists.
+func RedactConnector(conn storage.Connector) (storage.Connector, error) {
+	if len(conn.Config) == 0 {
+		return conn, nil
+	}
+
+	// Decode numbers as json.Number so they're written back unchanged rather
+	// than rounded through a float64.
+	d := json.NewDecoder(bytes.NewReader(conn.Config))
+	d.UseNumber()
+	var config map[string]interface{}
+	if err := d.Decode(&config); err != nil {
+		return storage.Connector{}, fmt.Errorf("parse connector config: %v", err)
+	}
+	redactSecrets(config)
+
+	data, err := json.Marshal(config)
+	if err != nil {
+		return storage.Connector{}, fmt.Errorf("marshal connector config: %v", err)
+	}
+	conn.Config = data
+	return conn, nil
+}
+
+// redactSecrets replaces non-empty string values of secret fields in a decoded
+// JSON value, recursing into objects and lists.
+func redactSecrets(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if s, ok := val.(string); ok {
+				if s != "" && connectorSecretFields[strings.ToLower(key)] {
+					v[key] = RedactedSecret
+				}
+				continue
+			}
+			redactSecrets(val)
+		}
+	case []interface{}:
+		for _, val := range v {
+			redactSecrets(val)
+		}
+	}
+}
+
+// MigrateConnectorType converts a stored connector to a different connector type
+// in a single update, preserving its ID so existing references, such as refresh
+// tokens, remain valid.
+//
+// transform is passed the stored connector and returns the config for the new
+// type, which must parse as that type's configuration. The connector is given a
+// new resource version so servers reopen it.
+func MigrateConnectorType(s storage.Storage, id, newType string, transform func(old storage.Connector) ([]byte, error)) error {
+	f, ok := ConnectorsConfig[newType]
+	if !ok {
+		return fmt.Errorf("unknown connector type %q", newType)
+	}
+	return s.UpdateConnector(id, func(old storage.Connector) (storage.Connector, error) {
+		config, err := transform(old)
+		if err != nil {
+			return old, err
+		}
+		if len(config) != 0 {
+			if err := json.Unmarshal(config, f()); err != nil {
+				return old, fmt.Errorf("parse %s connector config: %v", newType, err)
+			}
+		}
+		old.Type = newType
+		old.Config = config
+		old.ResourceVersion = storage.NewID()
+		return old, nil
+	})
+}
+
 // OpenConnector updates server connector map with specified connector object.
 func (s *Server) OpenConnector(conn storage.Connector) (Connector, error) {
+	if singletonConnectorTypes[conn.Type] {
+		// Connectors can be added to the storage after the server starts, so
+		// check singleton types each time one is opened.
+		conns, err := s.storage.ListConnectors()
+		if err != nil {
+			return Connector{}, fmt.Errorf("failed to list connectors: %v", err)
+		}
+		if err := checkSingletonConnectors(withConnector(conns, conn)); err != nil {
+			return Connector{}, err
+		}
+	}
+
 	var c connector.Connector
 
 	if conn.Type == LocalConnector {
@@ -493,6 +681,18 @@ func (s *Server) OpenConnector(conn storage.Connector) (Connector, error) {
 	return connector, nil
 }
 
+// withConnector returns the list with conn in place of the connector with the
+// same ID, or appended if there's none.
+func withConnector(conns []storage.Connector, conn storage.Connector) []storage.Connector {
+	for i, c := range conns {
+		if c.ID == conn.ID {
+			conns[i] = conn
+			return conns
+		}
+	}
+	return append(conns, conn)
+}
+
 // getConnector retrieves the connector object with the given id from the storage
 // and updates the connector list for server if necessary.
 func (s *Server) getConnector(id string) (Connector, error) {
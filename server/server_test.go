@@ -1,6 +1,7 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"crypto/rsa"
 	"crypto/x509"
@@ -1029,6 +1030,259 @@ func TestPasswordDBUsernamePrompt(t *testing.T) {
 	}
 }
 
+func TestRedactConnector(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name:   "oauth2 client secret",
+			config: `{"clientID":"foo","clientSecret":"bar","redirectURI":"https://example.com/callback"}`,
+			want:   `{"clientID":"foo","clientSecret":"******","redirectURI":"https://example.com/callback"}`,
+		},
+		{
+			name:   "ldap bind password",
+			config: `{"host":"ldap.example.com:636","bindDN":"cn=admin","bindPW":"admin"}`,
+			want:   `{"bindDN":"cn=admin","bindPW":"******","host":"ldap.example.com:636"}`,
+		},
+		{
+			name:   "empty secret is left alone",
+			config: `{"clientID":"foo","clientSecret":""}`,
+			want:   `{"clientID":"foo","clientSecret":""}`,
+		},
+		{
+			name:   "nested secrets",
+			config: `{"upstream":{"clientSecret":"bar"},"servers":[{"host":"a","password":"baz"}]}`,
+			want:   `{"servers":[{"host":"a","password":"******"}],"upstream":{"clientSecret":"******"}}`,
+		},
+		{
+			name:   "mixed case keys",
+			config: `{"ClientSecret":"bar","bindpw":"admin","PASSWORD":"baz"}`,
+			want:   `{"ClientSecret":"******","PASSWORD":"******","bindpw":"******"}`,
+		},
+		{
+			name:   "numbers are preserved",
+			config: `{"clientSecret":"bar","id":12345678901234567890,"ratio":0.1}`,
+			want:   `{"clientSecret":"******","id":12345678901234567890,"ratio":0.1}`,
+		},
+	}
+
+	for _, tc := range tests {
+		conn := storage.Connector{ID: "test", Type: "oidc", Config: []byte(tc.config)}
+		got, err := RedactConnector(conn)
+		if err != nil {
+			t.Errorf("%s: redact connector: %v", tc.name, err)
+			continue
+		}
+		if string(got.Config) != tc.want {
+			t.Errorf("%s: want=%s, got=%s", tc.name, tc.want, got.Config)
+		}
+		if string(conn.Config) != tc.config {
+			t.Errorf("%s: original connector config was modified", tc.name)
+		}
+	}
+}
+
+func TestMigrateConnectorType(t *testing.T) {
+	s := memory.New(logger)
+	conn := storage.Connector{
+		ID:              "upstream",
+		Type:            "mockCallback",
+		Name:            "Upstream",
+		ResourceVersion: "1",
+	}
+	if err := s.CreateConnector(conn); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+
+	toPassword := func(old storage.Connector) ([]byte, error) {
+		return []byte(`{"username":"foo","password":"bar"}`), nil
+	}
+	if err := MigrateConnectorType(s, conn.ID, "unknown", toPassword); err == nil {
+		t.Errorf("expected error migrating to an unknown connector type")
+	}
+	if err := MigrateConnectorType(s, conn.ID, "mockPassword", func(old storage.Connector) ([]byte, error) {
+		return []byte(`{"username":`), nil
+	}); err == nil {
+		t.Errorf("expected error migrating to an invalid config")
+	}
+	if err := MigrateConnectorType(s, conn.ID, "mockPassword", toPassword); err != nil {
+		t.Fatalf("migrate connector type: %v", err)
+	}
+
+	got, err := s.GetConnector(conn.ID)
+	if err != nil {
+		t.Fatalf("get connector: %v", err)
+	}
+	if got.Type != "mockPassword" || got.Name != conn.Name {
+		t.Errorf("unexpected migrated connector: %+v", got)
+	}
+	if got.ResourceVersion == conn.ResourceVersion {
+		t.Errorf("expected resource version to change")
+	}
+}
+
+func TestLogClientID(t *testing.T) {
+	s := &Server{}
+	if got := s.logClientID("example-app"); got != "example-app" {
+		t.Errorf("expected plain client ID without a salt, got %q", got)
+	}
+
+	s.clientIDLogSalt = "salt"
+	got := s.logClientID("example-app")
+	if got == "example-app" {
+		t.Errorf("expected client ID to be hashed")
+	}
+	if got != HashClientIDForLog("salt", "example-app") {
+		t.Errorf("expected hashed client ID to be stable")
+	}
+	if got == HashClientIDForLog("other-salt", "example-app") {
+		t.Errorf("expected hashed client ID to depend on the salt")
+	}
+
+	// The API logs client IDs the same way as the server.
+	if got := clientIDForLog("salt", "example-app"); got != s.logClientID("example-app") {
+		t.Errorf("expected API and server to hash client IDs the same way, got %q", got)
+	}
+}
+
+func TestLogClientIDAuthorizationErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	buff := new(bytes.Buffer)
+	httpServer, s := newTestServer(ctx, t, func(c *Config) {
+		c.ClientIDLogSalt = "salt"
+		c.Logger = &logrus.Logger{
+			Out:       buff,
+			Formatter: &logrus.TextFormatter{DisableColors: true},
+			Level:     logrus.DebugLevel,
+		}
+	})
+	defer httpServer.Close()
+
+	client := storage.Client{
+		ID:           "known-client-id",
+		Secret:       "secret",
+		RedirectURIs: []string{"https://example.com/callback"},
+	}
+	if err := s.storage.CreateClient(client); err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    url.Values
+		clientID string
+	}{
+		{
+			name: "unknown client",
+			query: url.Values{
+				"client_id":     {"unknown-client-id"},
+				"redirect_uri":  {"https://example.com/callback"},
+				"response_type": {"code"},
+				"scope":         {"openid"},
+			},
+			clientID: "unknown-client-id",
+		},
+		{
+			name: "untrusted peer",
+			query: url.Values{
+				"client_id":     {client.ID},
+				"redirect_uri":  {"https://example.com/callback"},
+				"response_type": {"code"},
+				"scope":         {"openid audience:server:client_id:untrusted-peer-id"},
+			},
+			clientID: "untrusted-peer-id",
+		},
+	}
+	for _, tc := range tests {
+		buff.Reset()
+		rr := httptest.NewRecorder()
+		s.ServeHTTP(rr, httptest.NewRequest("GET", "/auth?"+tc.query.Encode(), nil))
+
+		logs := buff.String()
+		if !strings.Contains(logs, "Failed to parse authorization request") {
+			t.Errorf("%s: expected authorization error to be logged, got %q", tc.name, logs)
+			continue
+		}
+		if strings.Contains(logs, tc.clientID) {
+			t.Errorf("%s: expected client ID %q not to be logged, got %q", tc.name, tc.clientID, logs)
+		}
+		if !strings.Contains(logs, HashClientIDForLog("salt", tc.clientID)) {
+			t.Errorf("%s: expected hashed client ID to be logged, got %q", tc.name, logs)
+		}
+	}
+}
+
+func TestCheckSingletonConnectors(t *testing.T) {
+	tests := []struct {
+		name    string
+		conns   []storage.Connector
+		wantErr bool
+	}{
+		{
+			name: "no local connector",
+			conns: []storage.Connector{
+				{ID: "mock1", Type: "mockCallback"},
+				{ID: "mock2", Type: "mockCallback"},
+			},
+		},
+		{
+			name: "one local connector",
+			conns: []storage.Connector{
+				{ID: "mock", Type: "mockCallback"},
+				{ID: LocalConnector, Type: LocalConnector},
+			},
+		},
+		{
+			name: "two local connectors",
+			conns: []storage.Connector{
+				{ID: LocalConnector, Type: LocalConnector},
+				{ID: "passwords", Type: LocalConnector},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		err := checkSingletonConnectors(tc.conns)
+		if err != nil && !tc.wantErr {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+		if err == nil && tc.wantErr {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+}
+
+func TestOpenSingletonConnector(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	httpServer, s := newTestServer(ctx, t, func(c *Config) {
+		local := storage.Connector{ID: LocalConnector, Type: LocalConnector, Name: "Email"}
+		if err := c.Storage.CreateConnector(local); err != nil {
+			t.Fatalf("create connector: %v", err)
+		}
+	})
+	defer httpServer.Close()
+
+	if _, err := s.getConnector(LocalConnector); err != nil {
+		t.Fatalf("get connector: %v", err)
+	}
+
+	// A second local connector added after the server started must not open.
+	second := storage.Connector{ID: "passwords", Type: LocalConnector, Name: "Passwords"}
+	if err := s.storage.CreateConnector(second); err != nil {
+		t.Fatalf("create connector: %v", err)
+	}
+	if _, err := s.getConnector(second.ID); err == nil {
+		t.Errorf("expected opening a second local connector to fail")
+	}
+}
+
 type storageWithKeysTrigger struct {
 	storage.Storage
 	f func()
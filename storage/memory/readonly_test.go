@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/coreos/dex/storage"
+	"github.com/sirupsen/logrus"
+)
+
+func TestReadOnly(t *testing.T) {
+	logger := &logrus.Logger{
+		Out:       os.Stderr,
+		Formatter: &logrus.TextFormatter{DisableColors: true},
+		Level:     logrus.DebugLevel,
+	}
+	backing := New(logger)
+
+	c1 := storage.Client{ID: "foo", Secret: "foo_secret"}
+	if err := backing.CreateClient(c1); err != nil {
+		t.Fatal(err)
+	}
+	s := storage.WithReadOnly(backing)
+
+	tests := []struct {
+		name    string
+		action  func() error
+		wantErr bool
+	}{
+		{
+			name: "get client",
+			action: func() error {
+				_, err := s.GetClient(c1.ID)
+				return err
+			},
+		},
+		{
+			name: "list clients",
+			action: func() error {
+				_, err := s.ListClients()
+				return err
+			},
+		},
+		{
+			name: "create client",
+			action: func() error {
+				return s.CreateClient(storage.Client{ID: "bar", Secret: "bar_secret"})
+			},
+			wantErr: true,
+		},
+		{
+			name: "update client",
+			action: func() error {
+				return s.UpdateClient(c1.ID, func(c storage.Client) (storage.Client, error) {
+					c.Secret = "new_" + c.Secret
+					return c, nil
+				})
+			},
+			wantErr: true,
+		},
+		{
+			name: "delete client",
+			action: func() error {
+				return s.DeleteClient(c1.ID)
+			},
+			wantErr: true,
+		},
+		{
+			name: "garbage collect",
+			action: func() error {
+				_, err := s.GarbageCollect(time.Now())
+				return err
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		err := tc.action()
+		if err != nil && !tc.wantErr {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if tc.wantErr && err != storage.ErrReadOnly {
+			t.Errorf("%s: expected storage.ErrReadOnly, got %v", tc.name, err)
+		}
+	}
+
+	// Writes must not reach the backing storage.
+	got, err := backing.GetClient(c1.ID)
+	if err != nil {
+		t.Fatalf("get client from backing storage: %v", err)
+	}
+	if got.Secret != c1.Secret {
+		t.Errorf("expected backing client to be unchanged, got secret %q", got.Secret)
+	}
+	if _, err := backing.GetClient("bar"); err != storage.ErrNotFound {
+		t.Errorf("expected client not to be created in backing storage, got %v", err)
+	}
+}
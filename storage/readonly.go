@@ -0,0 +1,68 @@
+package storage
+
+import "time"
+
+// Tests for this code are in the "memory" package, since this package doesn't
+// define a concrete storage implementation.
+
+// readOnlyStorage is a storage which rejects every write with ErrReadOnly
+// without passing it to the underlying storage.
+type readOnlyStorage struct {
+	Storage
+}
+
+// WithReadOnly returns a storage which serves reads from the underlying storage
+// and fails all writes with ErrReadOnly. This is intended for instances pointed
+// at a read-only database, such as a replica, where writes should fail fast
+// rather than with a database error.
+func WithReadOnly(s Storage) Storage {
+	return readOnlyStorage{s}
+}
+
+func (readOnlyStorage) CreateAuthRequest(a AuthRequest) error         { return ErrReadOnly }
+func (readOnlyStorage) CreateClient(c Client) error                   { return ErrReadOnly }
+func (readOnlyStorage) CreateAuthCode(c AuthCode) error               { return ErrReadOnly }
+func (readOnlyStorage) CreateRefresh(r RefreshToken) error            { return ErrReadOnly }
+func (readOnlyStorage) CreatePassword(p Password) error               { return ErrReadOnly }
+func (readOnlyStorage) CreateOfflineSessions(s OfflineSessions) error { return ErrReadOnly }
+func (readOnlyStorage) CreateConnector(c Connector) error             { return ErrReadOnly }
+
+func (readOnlyStorage) DeleteAuthRequest(id string) error                 { return ErrReadOnly }
+func (readOnlyStorage) DeleteAuthCode(code string) error                  { return ErrReadOnly }
+func (readOnlyStorage) DeleteClient(id string) error                      { return ErrReadOnly }
+func (readOnlyStorage) DeleteRefresh(id string) error                     { return ErrReadOnly }
+func (readOnlyStorage) DeletePassword(email string) error                 { return ErrReadOnly }
+func (readOnlyStorage) DeleteOfflineSessions(userID, connID string) error { return ErrReadOnly }
+func (readOnlyStorage) DeleteConnector(id string) error                   { return ErrReadOnly }
+
+func (readOnlyStorage) UpdateClient(id string, updater func(old Client) (Client, error)) error {
+	return ErrReadOnly
+}
+
+func (readOnlyStorage) UpdateKeys(updater func(old Keys) (Keys, error)) error {
+	return ErrReadOnly
+}
+
+func (readOnlyStorage) UpdateAuthRequest(id string, updater func(a AuthRequest) (AuthRequest, error)) error {
+	return ErrReadOnly
+}
+
+func (readOnlyStorage) UpdateRefreshToken(id string, updater func(r RefreshToken) (RefreshToken, error)) error {
+	return ErrReadOnly
+}
+
+func (readOnlyStorage) UpdatePassword(email string, updater func(p Password) (Password, error)) error {
+	return ErrReadOnly
+}
+
+func (readOnlyStorage) UpdateOfflineSessions(userID, connID string, updater func(s OfflineSessions) (OfflineSessions, error)) error {
+	return ErrReadOnly
+}
+
+func (readOnlyStorage) UpdateConnector(id string, updater func(c Connector) (Connector, error)) error {
+	return ErrReadOnly
+}
+
+func (readOnlyStorage) GarbageCollect(now time.Time) (GCResult, error) {
+	return GCResult{}, ErrReadOnly
+}
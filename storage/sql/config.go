@@ -2,9 +2,14 @@ package sql
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"net"
 	"net/url"
+	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/coreos/dex/storage"
 	"github.com/lib/pq"
@@ -14,9 +19,38 @@ import (
 
 const (
 	// postgres error codes
-	pgErrUniqueViolation = "23505" // unique_violation
+	pgErrUniqueViolation      = "23505" // unique_violation
+	pgErrSerializationFailure = "40001" // serialization_failure
+	pgErrDeadlockDetected     = "40P01" // deadlock_detected
+	pgErrCannotConnectNow     = "57P03" // cannot_connect_now
+
+	// postgres error classes
+	pgErrClassConnectionException = "08" // connection_exception
 )
 
+// isPostgresRetryableErr reports if the error aborted a transaction in a way
+// that means the transaction can be retried.
+func isPostgresRetryableErr(err error) bool {
+	sqlErr, ok := err.(*pq.Error)
+	if !ok {
+		return false
+	}
+	return sqlErr.Code == pgErrSerializationFailure || sqlErr.Code == pgErrDeadlockDetected
+}
+
+// isPostgresTransientErr reports if the error means the database couldn't be
+// reached or isn't ready yet, such as while it's starting up, rather than that
+// a statement failed.
+func isPostgresTransientErr(err error) bool {
+	switch err := err.(type) {
+	case net.Error:
+		return true
+	case *pq.Error:
+		return err.Code.Class() == pgErrClassConnectionException || err.Code == pgErrCannotConnectNow
+	}
+	return err == driver.ErrBadConn
+}
+
 // SQLite3 options for creating an SQL db.
 type SQLite3 struct {
 	// File to
@@ -32,7 +66,53 @@ func (s *SQLite3) Open(logger logrus.FieldLogger) (storage.Storage, error) {
 	return conn, nil
 }
 
+// PendingMigrations returns the SQL statements of the migrations which haven't
+// been applied to the database yet, in the order they would run, without
+// running them.
+//
+// A database file which doesn't exist yet has every migration pending, and isn't
+// created.
+func (s *SQLite3) PendingMigrations(logger logrus.FieldLogger) ([]string, error) {
+	if s.File == ":memory:" {
+		return flavorSQLite3.migrationsFrom(0), nil
+	}
+	if _, err := os.Stat(sqlite3Path(s.File)); os.IsNotExist(err) {
+		return flavorSQLite3.migrationsFrom(0), nil
+	}
+
+	c, err := s.connect(logger)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.pendingMigrations()
+}
+
+// sqlite3Path returns the path of the database file named by an SQLite3 file
+// option, which may be a "file:" URI with query parameters.
+func sqlite3Path(file string) string {
+	if strings.HasPrefix(file, "file:") {
+		file = strings.TrimPrefix(file, "file:")
+		if i := strings.Index(file, "?"); i >= 0 {
+			file = file[:i]
+		}
+	}
+	return file
+}
+
 func (s *SQLite3) open(logger logrus.FieldLogger) (*conn, error) {
+	c, err := s.connect(logger)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.migrate(); err != nil {
+		return nil, fmt.Errorf("failed to perform migrations: %v", err)
+	}
+	return c, nil
+}
+
+// connect returns a connection to the database without performing migrations.
+func (s *SQLite3) connect(logger logrus.FieldLogger) (*conn, error) {
 	db, err := sql.Open("sqlite3", s.File)
 	if err != nil {
 		return nil, err
@@ -51,11 +131,7 @@ func (s *SQLite3) open(logger logrus.FieldLogger) (*conn, error) {
 		return sqlErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
 	}
 
-	c := &conn{db, flavorSQLite3, logger, errCheck}
-	if _, err := c.migrate(); err != nil {
-		return nil, fmt.Errorf("failed to perform migrations: %v", err)
-	}
-	return c, nil
+	return &conn{db, flavorSQLite3, logger, errCheck, nil}, nil
 }
 
 const (
@@ -84,6 +160,14 @@ type Postgres struct {
 	SSL PostgresSSL `json:"ssl" yaml:"ssl"`
 
 	ConnectionTimeout int // Seconds
+
+	// Number of times to retry migrations at startup if the database can't be
+	// reached, such as when it's still starting up. Defaults to no retries.
+	MigrationRetries int
+
+	// Prepare queries run outside of transactions once and reuse the
+	// statements, instead of having the server parse them on every call.
+	PrepareStatements bool
 }
 
 // Open creates a new storage implementation backed by Postgres.
@@ -95,7 +179,35 @@ func (p *Postgres) Open(logger logrus.FieldLogger) (storage.Storage, error) {
 	return conn, nil
 }
 
+// PendingMigrations returns the SQL statements of the migrations which haven't
+// been applied to the database yet, in the order they would run, without
+// running them.
+func (p *Postgres) PendingMigrations(logger logrus.FieldLogger) ([]string, error) {
+	c, err := p.connect(logger)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.pendingMigrations()
+}
+
 func (p *Postgres) open(logger logrus.FieldLogger) (*conn, error) {
+	c, err := p.connect(logger)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.migrateWithRetries(p.MigrationRetries, time.Second, isPostgresTransientErr); err != nil {
+		return nil, fmt.Errorf("failed to perform migrations: %v", err)
+	}
+	if p.PrepareStatements {
+		// Enabled after migrations so one-off schema changes aren't cached.
+		c.stmts = newStmtCache()
+	}
+	return c, nil
+}
+
+// connect returns a connection to the database without performing migrations.
+func (p *Postgres) connect(logger logrus.FieldLogger) (*conn, error) {
 	v := url.Values{}
 	set := func(key, val string) {
 		if val != "" {
@@ -139,9 +251,5 @@ func (p *Postgres) open(logger logrus.FieldLogger) (*conn, error) {
 		return sqlErr.Code == pgErrUniqueViolation
 	}
 
-	c := &conn{db, flavorPostgres, logger, errCheck}
-	if _, err := c.migrate(); err != nil {
-		return nil, fmt.Errorf("failed to perform migrations: %v", err)
-	}
-	return c, nil
+	return &conn{db, flavorPostgres, logger, errCheck, nil}, nil
 }
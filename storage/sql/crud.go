@@ -76,7 +76,7 @@ func (j jsonDecoder) Scan(dest interface{}) error {
 
 // Abstract conn vs trans.
 type querier interface {
-	QueryRow(query string, args ...interface{}) *sql.Row
+	QueryRow(query string, args ...interface{}) scanner
 }
 
 // Abstract row vs rows.
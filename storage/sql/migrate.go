@@ -3,17 +3,60 @@ package sql
 import (
 	"database/sql"
 	"fmt"
+	"time"
 )
 
+// Upper bound of the backoff between migration attempts.
+const maxMigrationBackoff = 30 * time.Second
+
+// migrateWithRetries performs migrations, retrying up to retries times if they
+// fail with an error transient reports as temporary, such as the database not
+// accepting connections yet. The backoff between attempts doubles each time, up
+// to maxMigrationBackoff. Other errors are returned immediately.
+func (c *conn) migrateWithRetries(retries int, backoff time.Duration, transient func(err error) bool) error {
+	for attempt := 0; ; attempt++ {
+		_, err := c.migrate()
+		if err == nil || attempt >= retries || !transient(migrateCause(err)) {
+			return err
+		}
+		c.logger.Errorf("failed to perform migrations, retrying in %v: %v", backoff, err)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxMigrationBackoff {
+			backoff = maxMigrationBackoff
+		}
+	}
+}
+
+// migrateErr annotates an error returned while migrating, keeping the driver's
+// error so migrateWithRetries can classify it.
+type migrateErr struct {
+	msg string
+	err error
+}
+
+func (e migrateErr) Error() string {
+	return e.msg + ": " + e.err.Error()
+}
+
+// migrateCause returns the error underlying an error returned by migrate.
+func migrateCause(err error) error {
+	if e, ok := err.(migrateErr); ok {
+		return e.err
+	}
+	return err
+}
+
+const createMigrationsTable = `
+	create table if not exists migrations (
+		num integer not null,
+		at timestamptz not null
+	);
+`
+
 func (c *conn) migrate() (int, error) {
-	_, err := c.Exec(`
-		create table if not exists migrations (
-			num integer not null,
-			at timestamptz not null
-		);
-	`)
+	_, err := c.Exec(createMigrationsTable)
 	if err != nil {
-		return 0, fmt.Errorf("creating migration table: %v", err)
+		return 0, migrateErr{"creating migration table", err}
 	}
 
 	i := 0
@@ -26,7 +69,7 @@ func (c *conn) migrate() (int, error) {
 				n   int
 			)
 			if err := tx.QueryRow(`select max(num) from migrations;`).Scan(&num); err != nil {
-				return fmt.Errorf("select max migration: %v", err)
+				return migrateErr{"select max migration", err}
 			}
 			if num.Valid {
 				n = int(num.Int64)
@@ -39,12 +82,12 @@ func (c *conn) migrate() (int, error) {
 			migrationNum := n + 1
 			m := migrations[n]
 			if _, err := tx.Exec(m.stmt); err != nil {
-				return fmt.Errorf("migration %d failed: %v", migrationNum, err)
+				return migrateErr{fmt.Sprintf("migration %d failed", migrationNum), err}
 			}
 
 			q := `insert into migrations (num, at) values ($1, now());`
 			if _, err := tx.Exec(q, migrationNum); err != nil {
-				return fmt.Errorf("update migration table: %v", err)
+				return migrateErr{"update migration table", err}
 			}
 			return nil
 		})
@@ -60,6 +103,43 @@ func (c *conn) migrate() (int, error) {
 	return i, nil
 }
 
+// pendingMigrations returns the translated statements of the migrations migrate
+// would run. The migrations table is read in a transaction which is always
+// rolled back, so the database is left unchanged even if the table has to be
+// created to read it.
+func (c *conn) pendingMigrations() ([]string, error) {
+	sqlTx, err := c.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer sqlTx.Rollback()
+	tx := &trans{tx: sqlTx, c: c}
+
+	if _, err := tx.Exec(createMigrationsTable); err != nil {
+		return nil, fmt.Errorf("creating migration table: %v", err)
+	}
+	var num sql.NullInt64
+	if err := tx.QueryRow(`select max(num) from migrations;`).Scan(&num); err != nil {
+		return nil, fmt.Errorf("select max migration: %v", err)
+	}
+
+	n := 0
+	if num.Valid {
+		n = int(num.Int64)
+	}
+	return c.flavor.migrationsFrom(n), nil
+}
+
+// migrationsFrom returns the translated statements of the migrations after the
+// first n.
+func (f flavor) migrationsFrom(n int) []string {
+	var stmts []string
+	for i := n; i < len(migrations); i++ {
+		stmts = append(stmts, f.translate(migrations[i].stmt))
+	}
+	return stmts
+}
+
 type migration struct {
 	stmt string
 	// TODO(ericchiang): consider adding additional fields like "forDrivers"
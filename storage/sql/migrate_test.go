@@ -2,9 +2,15 @@ package sql
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/lib/pq"
 	sqlite3 "github.com/mattn/go-sqlite3"
 	"github.com/sirupsen/logrus"
 )
@@ -30,7 +36,7 @@ func TestMigrate(t *testing.T) {
 		return sqlErr.ExtendedCode == sqlite3.ErrConstraintUnique
 	}
 
-	c := &conn{db, flavorSQLite3, logger, errCheck}
+	c := &conn{db, flavorSQLite3, logger, errCheck, nil}
 	for _, want := range []int{len(migrations), 0} {
 		got, err := c.migrate()
 		if err != nil {
@@ -41,3 +47,127 @@ func TestMigrate(t *testing.T) {
 		}
 	}
 }
+
+func TestMigrateWithRetries(t *testing.T) {
+	logger := &logrus.Logger{
+		Out:       os.Stderr,
+		Formatter: &logrus.TextFormatter{DisableColors: true},
+		Level:     logrus.DebugLevel,
+	}
+
+	newConn := func() *conn {
+		db, err := sql.Open("sqlite3", ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.SetMaxOpenConns(1)
+		return &conn{db, flavorSQLite3, logger, func(err error) bool { return false }, nil}
+	}
+
+	// Treat errors from a closed database as transient, standing in for a
+	// database which can't be reached yet.
+	transient := func(err error) bool {
+		return strings.Contains(err.Error(), "database is closed")
+	}
+
+	// Statements which fail shouldn't be retried, otherwise this would wait for
+	// an hour.
+	c := newConn()
+	defer c.Close()
+	if _, err := c.Exec(`create table migrations (foo text);`); err != nil {
+		t.Fatal(err)
+	}
+	withTimeout(time.Second*10, func() {
+		if err := c.migrateWithRetries(3, time.Hour, transient); err == nil {
+			t.Errorf("expected migrations to fail")
+		}
+	})
+
+	// Transient errors are retried before giving up.
+	c = newConn()
+	c.Close()
+	start := time.Now()
+	if err := c.migrateWithRetries(2, 10*time.Millisecond, transient); err == nil {
+		t.Errorf("expected migrations against a closed database to fail")
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("expected migrations to be retried, returned after %v", elapsed)
+	}
+
+	c = newConn()
+	defer c.Close()
+	if err := c.migrateWithRetries(2, time.Hour, transient); err != nil {
+		t.Errorf("migrate: %v", err)
+	}
+}
+
+func TestIsPostgresTransientErr(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"bad connection", driver.ErrBadConn, true},
+		{"connection failure", &pq.Error{Code: "08006"}, true},
+		{"starting up", &pq.Error{Code: "57P03"}, true},
+		{"unique violation", &pq.Error{Code: "23505"}, false},
+		{"syntax error", &pq.Error{Code: "42601"}, false},
+		{"other error", errors.New("foo"), false},
+	}
+	for _, tc := range tests {
+		if got := isPostgresTransientErr(tc.err); got != tc.want {
+			t.Errorf("%s: want=%t, got=%t", tc.name, tc.want, got)
+		}
+	}
+
+	// migrate annotates errors, the cause must still be classified.
+	err := migrateErr{"creating migration table", &pq.Error{Code: "57P03"}}
+	if !isPostgresTransientErr(migrateCause(err)) {
+		t.Errorf("expected annotated error to be transient")
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	logger := &logrus.Logger{
+		Out:       os.Stderr,
+		Formatter: &logrus.TextFormatter{DisableColors: true},
+		Level:     logrus.DebugLevel,
+	}
+	c := &conn{db, flavorSQLite3, logger, func(err error) bool { return false }, nil}
+
+	pending, err := c.pendingMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != len(migrations) {
+		t.Errorf("expected %d pending migrations, got %d", len(migrations), len(pending))
+	}
+
+	// A dry run must not create the migrations table.
+	var n int
+	if err := db.QueryRow(`select count(*) from sqlite_master where name = 'migrations';`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("expected pending migrations not to create the migrations table")
+	}
+
+	if _, err := c.migrate(); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = c.pendingMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected no pending migrations after migrating, got %d", len(pending))
+	}
+}
@@ -4,9 +4,11 @@ package sql
 import (
 	"database/sql"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/cockroachdb/cockroach-go/crdb"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 
 	// import third party drivers
@@ -32,6 +34,13 @@ type flavor struct {
 
 	// Does the flavor support timezones?
 	supportsTimezones bool
+
+	// Optional function which reports if an error aborted a transaction in a
+	// way that makes it safe to retry, such as a serialization failure.
+	//
+	// Flavors which retry transactions themselves through executeTx leave
+	// this nil.
+	retryableErr func(err error) bool
 }
 
 // A regexp with a replacement string.
@@ -75,6 +84,8 @@ var (
 		},
 
 		supportsTimezones: true,
+
+		retryableErr: isPostgresRetryableErr,
 	}
 
 	flavorSQLite3 = flavor{
@@ -135,42 +146,202 @@ type conn struct {
 	flavor             flavor
 	logger             logrus.FieldLogger
 	alreadyExistsCheck func(err error) bool
+
+	// Prepared statements for queries run outside of transactions. If nil,
+	// queries are sent to the database as text each time.
+	stmts *stmtCache
 }
 
 func (c *conn) Close() error {
+	if c.stmts != nil {
+		c.stmts.close()
+	}
 	return c.db.Close()
 }
 
+// stmtCache holds prepared statements keyed by the untranslated query text.
+// Queries which failed to prepare are cached as nil statements so they're sent
+// as text without trying to prepare them again. Failures caused by the database
+// being unreachable aren't cached, so the query is prepared once it's back.
+type stmtCache struct {
+	mu    sync.RWMutex
+	stmts map[string]*sql.Stmt
+}
+
+func newStmtCache() *stmtCache {
+	return &stmtCache{stmts: make(map[string]*sql.Stmt)}
+}
+
+// prepare returns the cached statement for the query, preparing it on first use.
+// It returns a nil statement and no error if the query previously failed to
+// prepare with a permanent error.
+func (s *stmtCache) prepare(c *conn, query string) (*sql.Stmt, error) {
+	s.mu.RLock()
+	stmt, ok := s.stmts[query]
+	s.mu.RUnlock()
+	if ok {
+		return stmt, nil
+	}
+
+	// Prepare outside the lock so a slow round trip doesn't block lookups of
+	// other queries.
+	stmt, err := c.db.Prepare(c.flavor.translate(query))
+	if err != nil && isPostgresTransientErr(err) {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.stmts[query]; ok {
+		// Another caller prepared the query first.
+		if stmt != nil {
+			stmt.Close()
+		}
+		return cached, nil
+	}
+	s.stmts[query] = stmt
+	return stmt, err
+}
+
+func (s *stmtCache) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for query, stmt := range s.stmts {
+		if stmt != nil {
+			stmt.Close()
+		}
+		delete(s.stmts, query)
+	}
+}
+
+// Connection pool metrics, read from the database's statistics on each scrape.
+// Older versions of Go only report the number of open connections; the other
+// pool statistics are exported from stats_go111.go and stats_go115.go, with a
+// no-op fallback for older versions in stats_pre_go111.go.
+var descOpenConnections = prometheus.NewDesc(
+	"sql_open_connections",
+	"Number of established connections to the database, both in use and idle.",
+	nil, nil,
+)
+
+// Describe implements prometheus.Collector, allowing the connection pool to be
+// monitored.
+func (c *conn) Describe(ch chan<- *prometheus.Desc) {
+	ch <- descOpenConnections
+	describePoolStats(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *conn) Collect(ch chan<- prometheus.Metric) {
+	stats := c.db.Stats()
+	ch <- prometheus.MustNewConstMetric(descOpenConnections, prometheus.GaugeValue, float64(stats.OpenConnections))
+	collectPoolStats(ch, stats)
+}
+
 // conn implements the same method signatures as encoding/sql.DB.
+//
+// If statement caching is enabled, queries are run through prepared statements.
+// Queries which fail to prepare fall back to being sent as text, so the error
+// is reported the same way either way.
 
 func (c *conn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	if stmt := c.prepared(query); stmt != nil {
+		return stmt.Exec(c.translateArgs(args)...)
+	}
 	query = c.flavor.translate(query)
 	return c.db.Exec(query, c.translateArgs(args)...)
 }
 
 func (c *conn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt := c.prepared(query); stmt != nil {
+		return stmt.Query(c.translateArgs(args)...)
+	}
 	query = c.flavor.translate(query)
 	return c.db.Query(query, c.translateArgs(args)...)
 }
 
-func (c *conn) QueryRow(query string, args ...interface{}) *sql.Row {
+func (c *conn) QueryRow(query string, args ...interface{}) scanner {
+	if stmt := c.prepared(query); stmt != nil {
+		return stmt.QueryRow(c.translateArgs(args)...)
+	}
 	query = c.flavor.translate(query)
 	return c.db.QueryRow(query, c.translateArgs(args)...)
 }
 
+// prepared returns the prepared statement for the query, or nil if statement
+// caching is disabled or the query couldn't be prepared.
+func (c *conn) prepared(query string) *sql.Stmt {
+	if c.stmts == nil {
+		return nil
+	}
+	stmt, err := c.stmts.prepare(c, query)
+	if err != nil {
+		c.logger.Warnf("failed to prepare statement, sending it as text: %v", err)
+		return nil
+	}
+	return stmt
+}
+
+const (
+	// Number of times a transaction is retried if it fails with a retryable error.
+	maxTxRetries = 5
+	// Backoff before the first retry, doubled on each subsequent attempt.
+	txRetryBackoff = 10 * time.Millisecond
+)
+
 // ExecTx runs a method which operates on a transaction.
+//
+// If the flavor reports that the transaction failed with a retryable error,
+// such as a serialization failure, the whole transaction is run again. Callers
+// must be safe to call more than once.
 func (c *conn) ExecTx(fn func(tx *trans) error) error {
+	if c.flavor.retryableErr == nil {
+		_, err := c.execTx(fn)
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		retry, err := c.execTx(fn)
+		if err == nil || attempt >= maxTxRetries || !retry {
+			return err
+		}
+		c.logger.Debugf("retrying transaction after error: %v", err)
+		time.Sleep(txRetryBackoff << uint(attempt))
+	}
+}
+
+// execTx runs the transaction once and reports if it failed with an error the
+// flavor considers retryable. The error returned is the one returned by fn, or
+// by the database when beginning or committing the transaction.
+func (c *conn) execTx(fn func(tx *trans) error) (retry bool, err error) {
+	run := func(sqlTx *sql.Tx) error {
+		t := &trans{tx: sqlTx, c: c}
+		if err := fn(t); err != nil {
+			// Driver errors are generally wrapped before being returned, so
+			// check the errors the driver returned to the transaction.
+			retry = t.retryErr != nil
+			return err
+		}
+		return nil
+	}
+
 	if c.flavor.executeTx != nil {
-		return c.flavor.executeTx(c.db, func(sqlTx *sql.Tx) error {
-			return fn(&trans{sqlTx, c})
-		})
+		err = c.flavor.executeTx(c.db, run)
+	} else {
+		err = c.runTx(run)
 	}
+	if err != nil && !retry && c.flavor.retryableErr != nil {
+		retry = c.flavor.retryableErr(err)
+	}
+	return retry, err
+}
 
+func (c *conn) runTx(run func(sqlTx *sql.Tx) error) error {
 	sqlTx, err := c.db.Begin()
 	if err != nil {
 		return err
 	}
-	if err := fn(&trans{sqlTx, c}); err != nil {
+	if err := run(sqlTx); err != nil {
 		sqlTx.Rollback()
 		return err
 	}
@@ -180,21 +351,49 @@ func (c *conn) ExecTx(fn func(tx *trans) error) error {
 type trans struct {
 	tx *sql.Tx
 	c  *conn
+
+	// The first retryable error returned by the driver, if any. It's only
+	// used to decide if the transaction is retried.
+	retryErr error
 }
 
-// trans implements the same method signatures as encoding/sql.Tx.
+func (t *trans) observe(err error) {
+	if err != nil && t.retryErr == nil && t.c.flavor.retryableErr != nil && t.c.flavor.retryableErr(err) {
+		t.retryErr = err
+	}
+}
+
+// trans implements the same method signatures as encoding/sql.Tx, except that
+// QueryRow returns a row whose Scan errors are observed by the transaction.
 
 func (t *trans) Exec(query string, args ...interface{}) (sql.Result, error) {
 	query = t.c.flavor.translate(query)
-	return t.tx.Exec(query, t.c.translateArgs(args)...)
+	r, err := t.tx.Exec(query, t.c.translateArgs(args)...)
+	t.observe(err)
+	return r, err
 }
 
 func (t *trans) Query(query string, args ...interface{}) (*sql.Rows, error) {
 	query = t.c.flavor.translate(query)
-	return t.tx.Query(query, t.c.translateArgs(args)...)
+	rows, err := t.tx.Query(query, t.c.translateArgs(args)...)
+	t.observe(err)
+	return rows, err
 }
 
-func (t *trans) QueryRow(query string, args ...interface{}) *sql.Row {
+func (t *trans) QueryRow(query string, args ...interface{}) scanner {
 	query = t.c.flavor.translate(query)
-	return t.tx.QueryRow(query, t.c.translateArgs(args)...)
+	return row{t.tx.QueryRow(query, t.c.translateArgs(args)...), t}
+}
+
+// row is a row queried in a transaction. Errors from a single row query are
+// only returned by Scan, so it's where they have to be observed.
+type row struct {
+	*sql.Row
+	t *trans
+}
+
+func (r row) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	r.t.observe(err)
+	return err
 }
@@ -1,6 +1,20 @@
 package sql
 
-import "testing"
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	sqlite3 "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/coreos/dex/storage"
+)
 
 func TestTranslate(t *testing.T) {
 	tests := []struct {
@@ -53,3 +67,281 @@ func TestTranslate(t *testing.T) {
 		}
 	}
 }
+
+// collectedMetrics returns the names of the metrics collected from the
+// connection.
+func collectedMetrics(t *testing.T, c *conn) map[string]bool {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(c); err != nil {
+		t.Fatalf("register connection metrics: %v", err)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("gather metrics: %v", err)
+	}
+
+	got := make(map[string]bool)
+	for _, f := range families {
+		got[f.GetName()] = true
+	}
+	return got
+}
+
+func TestConnectionMetrics(t *testing.T) {
+	s := &SQLite3{":memory:"}
+	c, err := s.open(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if !collectedMetrics(t, c)["sql_open_connections"] {
+		t.Errorf("expected metric sql_open_connections to be collected")
+	}
+}
+
+func TestExecTxRetry(t *testing.T) {
+	s := &SQLite3{":memory:"}
+	c, err := s.open(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// Treat errRetry and unwrapped SQLite errors as retryable, so the driver
+	// errors observed by the transaction can be tested with SQLite.
+	errRetry := errors.New("retry me")
+	c.flavor.retryableErr = func(err error) bool {
+		_, ok := err.(sqlite3.Error)
+		return ok || err == errRetry
+	}
+
+	tests := []struct {
+		name          string
+		fail          func(tx *trans) error
+		failures      int
+		wantErr       bool
+		wantErrPrefix string
+		wantAttempts  int
+	}{
+		{
+			name:         "no failures",
+			wantAttempts: 1,
+		},
+		{
+			name:         "retried until success",
+			fail:         func(tx *trans) error { return errRetry },
+			failures:     2,
+			wantAttempts: 3,
+		},
+		{
+			name:         "gives up after max retries",
+			fail:         func(tx *trans) error { return errRetry },
+			failures:     maxTxRetries + 1,
+			wantErr:      true,
+			wantAttempts: maxTxRetries + 1,
+		},
+		{
+			name: "wrapped exec error",
+			fail: func(tx *trans) error {
+				if _, err := tx.Exec(`delete from missing_table;`); err != nil {
+					return fmt.Errorf("delete: %v", err)
+				}
+				return nil
+			},
+			failures:     2,
+			wantAttempts: 3,
+		},
+		{
+			name: "wrapped error is returned after max retries",
+			fail: func(tx *trans) error {
+				if _, err := tx.Exec(`delete from missing_table;`); err != nil {
+					return fmt.Errorf("delete: %v", err)
+				}
+				return nil
+			},
+			failures:      maxTxRetries + 1,
+			wantErr:       true,
+			wantErrPrefix: "delete: ",
+			wantAttempts:  maxTxRetries + 1,
+		},
+		{
+			name: "wrapped query row error",
+			fail: func(tx *trans) error {
+				var n int
+				if err := tx.QueryRow(`select count(*) from missing_table;`).Scan(&n); err != nil {
+					return fmt.Errorf("count: %v", err)
+				}
+				return nil
+			},
+			failures:     2,
+			wantAttempts: 3,
+		},
+		{
+			name: "other errors are not retried",
+			fail: func(tx *trans) error {
+				return errors.New("permanent")
+			},
+			failures:     1,
+			wantErr:      true,
+			wantAttempts: 1,
+		},
+	}
+	for _, tc := range tests {
+		attempts := 0
+		err := c.ExecTx(func(tx *trans) error {
+			attempts++
+			if attempts <= tc.failures {
+				return tc.fail(tx)
+			}
+			return nil
+		})
+		if gotErr := err != nil; gotErr != tc.wantErr {
+			t.Errorf("%s: wantErr=%t, got err=%v", tc.name, tc.wantErr, err)
+		}
+		if err != nil && !strings.HasPrefix(err.Error(), tc.wantErrPrefix) {
+			t.Errorf("%s: expected error starting with %q, got %v", tc.name, tc.wantErrPrefix, err)
+		}
+		if attempts != tc.wantAttempts {
+			t.Errorf("%s: expected %d attempts, got %d", tc.name, tc.wantAttempts, attempts)
+		}
+	}
+}
+
+// badConnDriver is a database driver which can never reach its database.
+type badConnDriver struct{}
+
+func (badConnDriver) Open(name string) (driver.Conn, error) {
+	return nil, driver.ErrBadConn
+}
+
+var badConnDB *sql.DB
+
+func init() {
+	sql.Register("dex-bad-conn", badConnDriver{})
+	badConnDB, _ = sql.Open("dex-bad-conn", "")
+}
+
+func TestStatementCache(t *testing.T) {
+	s := &SQLite3{":memory:"}
+	c, err := s.open(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.stmts = newStmtCache()
+
+	client := storage.Client{
+		ID:           "foo",
+		Secret:       "bar",
+		RedirectURIs: []string{"https://example.com/callback"},
+	}
+	if err := c.CreateClient(client); err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetClient(client.ID); err != nil {
+			t.Fatalf("get client: %v", err)
+		}
+	}
+	if _, err := c.GetClient("missing"); err != storage.ErrNotFound {
+		t.Errorf("expected storage.ErrNotFound getting missing client, got %v", err)
+	}
+	if n := len(c.stmts.stmts); n == 0 {
+		t.Errorf("expected queries to be cached")
+	}
+
+	// Queries which fail to prepare are still sent to the database, and the
+	// failure is remembered so they aren't prepared again.
+	badQuery := `select * from no_such_table;`
+	for i := 0; i < 2; i++ {
+		if _, err := c.Exec(badQuery); err == nil {
+			t.Errorf("expected querying a missing table to fail")
+		}
+	}
+	if stmt, ok := c.stmts.stmts[badQuery]; !ok || stmt != nil {
+		t.Errorf("expected failure to prepare query to be cached")
+	}
+
+	// Failures to reach the database aren't remembered, so the query is
+	// prepared again once it's reachable.
+	badConn := &conn{db: badConnDB, flavor: c.flavor, logger: logger, stmts: newStmtCache()}
+	if _, err := badConn.stmts.prepare(badConn, badQuery); err != driver.ErrBadConn {
+		t.Errorf("expected driver.ErrBadConn preparing with a bad connection, got %v", err)
+	}
+	if _, ok := badConn.stmts.stmts[badQuery]; ok {
+		t.Errorf("expected transient failure to prepare query not to be cached")
+	}
+
+	// Concurrent callers share a single statement per query.
+	n := len(c.stmts.stmts)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.ListClients(); err != nil {
+				t.Errorf("list clients: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := len(c.stmts.stmts); got != n+1 {
+		t.Errorf("expected one statement to be cached for concurrent queries, got %d", got-n)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if n := len(c.stmts.stmts); n != 0 {
+		t.Errorf("expected statements to be closed, %d remaining", n)
+	}
+}
+
+// BenchmarkGetClient compares client lookups sent as text with lookups using
+// prepared statements. Statement caching is only offered for Postgres, so the
+// benchmark runs against the database configured for TestPostgres.
+func BenchmarkGetClient(b *testing.B) {
+	host := os.Getenv(testPostgresEnv)
+	if host == "" {
+		b.Skipf("test environment variable %q not set, skipping", testPostgresEnv)
+	}
+	for _, bc := range []struct {
+		name    string
+		prepare bool
+	}{
+		{"text", false},
+		{"prepared", true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			p := Postgres{
+				Database: getenv("DEX_POSTGRES_DATABASE", "postgres"),
+				User:     getenv("DEX_POSTGRES_USER", "postgres"),
+				Password: getenv("DEX_POSTGRES_PASSWORD", "postgres"),
+				Host:     host,
+				SSL: PostgresSSL{
+					Mode: sslDisable, // Postgres container doesn't support SSL.
+				},
+				ConnectionTimeout: 5,
+				PrepareStatements: bc.prepare,
+			}
+			c, err := p.open(logger)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer c.Close()
+			if err := cleanDB(c); err != nil {
+				b.Fatal(err)
+			}
+			if err := c.CreateClient(storage.Client{ID: "foo", Secret: "bar"}); err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.GetClient("foo"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// +build go1.11
+
+package sql
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	descInUseConnections = prometheus.NewDesc(
+		"sql_in_use_connections",
+		"Number of connections currently in use.",
+		nil, nil,
+	)
+	descIdleConnections = prometheus.NewDesc(
+		"sql_idle_connections",
+		"Number of idle connections.",
+		nil, nil,
+	)
+	descWaitCount = prometheus.NewDesc(
+		"sql_wait_count_total",
+		"Count of times a query waited for a connection to become available.",
+		nil, nil,
+	)
+	descWaitDuration = prometheus.NewDesc(
+		"sql_wait_duration_seconds_total",
+		"Total time spent waiting for a connection to become available.",
+		nil, nil,
+	)
+	descClosedConnections = prometheus.NewDesc(
+		"sql_closed_connections_total",
+		"Count of connections closed by the pool, by reason.",
+		[]string{"reason"}, nil,
+	)
+)
+
+func describePoolStats(ch chan<- *prometheus.Desc) {
+	ch <- descInUseConnections
+	ch <- descIdleConnections
+	ch <- descWaitCount
+	ch <- descWaitDuration
+	ch <- descClosedConnections
+}
+
+func collectPoolStats(ch chan<- prometheus.Metric, stats sql.DBStats) {
+	ch <- prometheus.MustNewConstMetric(descInUseConnections, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(descIdleConnections, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(descWaitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(descWaitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+	ch <- prometheus.MustNewConstMetric(descClosedConnections, prometheus.CounterValue, float64(stats.MaxIdleClosed), "max_idle")
+	ch <- prometheus.MustNewConstMetric(descClosedConnections, prometheus.CounterValue, float64(stats.MaxLifetimeClosed), "max_lifetime")
+	collectIdleTimeClosed(ch, stats)
+}
@@ -0,0 +1,27 @@
+// +build go1.11
+
+package sql
+
+import "testing"
+
+func TestPoolStatsMetrics(t *testing.T) {
+	s := &SQLite3{":memory:"}
+	c, err := s.open(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	got := collectedMetrics(t, c)
+	for _, name := range []string{
+		"sql_in_use_connections",
+		"sql_idle_connections",
+		"sql_wait_count_total",
+		"sql_wait_duration_seconds_total",
+		"sql_closed_connections_total",
+	} {
+		if !got[name] {
+			t.Errorf("expected metric %s to be collected", name)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+// +build go1.15
+
+package sql
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func collectIdleTimeClosed(ch chan<- prometheus.Metric, stats sql.DBStats) {
+	ch <- prometheus.MustNewConstMetric(descClosedConnections, prometheus.CounterValue, float64(stats.MaxIdleTimeClosed), "max_idle_time")
+}
@@ -0,0 +1,15 @@
+// +build !go1.11
+
+package sql
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sql.DBStats only reports open connections before Go 1.11.
+
+func describePoolStats(ch chan<- *prometheus.Desc) {}
+
+func collectPoolStats(ch chan<- prometheus.Metric, stats sql.DBStats) {}
@@ -17,6 +17,9 @@ var (
 
 	// ErrAlreadyExists is the error returned by storages if a resource ID is taken during a create.
 	ErrAlreadyExists = errors.New("ID already exists")
+
+	// ErrReadOnly is the error returned by read-only storages for any write.
+	ErrReadOnly = errors.New("storage is read-only")
 )
 
 // Kubernetes only allows lower case letters for names.